@@ -0,0 +1,193 @@
+// Copyright 2024 CNI authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"crypto/sha512"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/containernetworking/cni/libcni"
+	"github.com/containernetworking/cni/pkg/invoke"
+)
+
+// attachFlags are the flags of add, check and del, which default to the
+// values of the protocol environment variables
+type attachFlags struct {
+	containerID    *string
+	ifName         *string
+	cniArgs        *string
+	capabilityArgs *string
+	capArgsFile    *string
+	pid            *int
+	cacheDir       *string
+	output         *string
+}
+
+func newAttachFlagSet(command, positional string, output *string) (*flag.FlagSet, *attachFlags) {
+	fs := newFlagSet(command, positional, output)
+	f := &attachFlags{
+		output:         output,
+		containerID:    fs.String("container-id", "", "container ID (default: derived from the netns path)"),
+		ifName:         fs.String("ifname", defaultIfName(), "interface name inside the container, $"+EnvCNIIfname),
+		cniArgs:        fs.String("args", os.Getenv(EnvCNIArgs), "CNI_ARGS as K=V pairs separated by ';', $"+EnvCNIArgs),
+		capabilityArgs: fs.String("capability-args", os.Getenv(EnvCapabilityArgs), "capability args as a JSON object, $"+EnvCapabilityArgs),
+		capArgsFile:    fs.String("capability-args-file", "", "file with capability args as a JSON object"),
+		pid:            fs.Int("pid", 0, "use the network namespace of this process instead of a netns path"),
+		cacheDir:       fs.String("cache-dir", libcni.CacheDir, "directory libcni caches results in"),
+	}
+	return fs, f
+}
+
+// runtimeConf builds the runtime configuration from the flags and the
+// positional arguments following the network name
+func (f *attachFlags) runtimeConf(command string, args []string) (*libcni.RuntimeConf, error) {
+	var netns string
+	switch {
+	case *f.pid != 0 && len(args) > 0:
+		return nil, fmt.Errorf("--pid and a netns path are mutually exclusive")
+	case *f.pid != 0:
+		netns = fmt.Sprintf("/proc/%d/ns/net", *f.pid)
+	case len(args) > 0:
+		path, err := filepath.Abs(args[0])
+		if err != nil {
+			return nil, err
+		}
+		netns = path
+	case command != CmdDel || *f.containerID == "":
+		// DEL is the only command allowed without a netns, e.g. after
+		// the container is gone, and it needs to know whose attachment
+		// to delete
+		return nil, fmt.Errorf("no network namespace given")
+	}
+
+	containerID := *f.containerID
+	if containerID == "" {
+		// Generate the containerid by hashing the netns path
+		s := sha512.Sum512([]byte(netns))
+		containerID = fmt.Sprintf("cnitool-%x", s[:10])
+	}
+
+	rt := &libcni.RuntimeConf{
+		ContainerID: containerID,
+		NetNS:       netns,
+		IfName:      *f.ifName,
+	}
+	if *f.cniArgs != "" {
+		cniArgs, err := parseArgs(*f.cniArgs)
+		if err != nil {
+			return nil, err
+		}
+		rt.Args = cniArgs
+	}
+
+	capabilityArgs := []byte(*f.capabilityArgs)
+	if *f.capArgsFile != "" {
+		data, err := os.ReadFile(*f.capArgsFile)
+		if err != nil {
+			return nil, err
+		}
+		capabilityArgs = data
+	}
+	if len(capabilityArgs) > 0 {
+		if err := json.Unmarshal(capabilityArgs, &rt.CapabilityArgs); err != nil {
+			return nil, fmt.Errorf("invalid capability args: %w", err)
+		}
+	}
+	return rt, nil
+}
+
+// attachCommand returns the function running add, check or del
+func attachCommand(command string) func(args []string, output *string) error {
+	return func(args []string, output *string) error {
+		fs, f := newAttachFlagSet(command, "<net> [<netns>]", output)
+		trace := fs.Bool("trace", false, "print each plugin execution to stderr")
+		traceFile := fs.String("trace-file", "", "write each plugin execution to this file as JSON")
+		record := fs.String("record", "", "append each plugin execution to this capture file, for cnitool replay")
+		redact := fs.String("redact", "", "comma-separated keys whose values are left out of the capture file")
+		recordAllEnv := fs.Bool("record-all-env", false, "record the whole environment rather than the CNI_ variables and PATH")
+		if err := parseFlags(fs, args, 1, 2, f.output); err != nil {
+			return err
+		}
+
+		netconf, err := loadNetConf(fs.Arg(0))
+		if err != nil {
+			return err
+		}
+		rt, err := f.runtimeConf(command, fs.Args()[1:])
+		if err != nil {
+			return err
+		}
+
+		verifier, err := pluginVerifier()
+		if err != nil {
+			return err
+		}
+		var exec invoke.Exec
+		var tracer *tracingExec
+		if *trace || *traceFile != "" {
+			tracer = &tracingExec{verifier: verifier}
+			if *trace {
+				tracer.out = os.Stderr
+			}
+			exec = tracer
+		}
+		if *record != "" {
+			capture, err := os.OpenFile(*record, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+			if err != nil {
+				return err
+			}
+			defer capture.Close()
+			recorder := &invoke.Recorder{Exec: exec, Stderr: os.Stderr, Verifier: verifier, Output: capture, AllEnv: *recordAllEnv}
+			if *redact != "" {
+				recorder.Redact = strings.Split(*redact, ",")
+			}
+			exec = recorder
+		}
+		cninet := libcni.NewCNIConfigWithOptions(filepath.SplitList(os.Getenv(EnvCNIPath)), exec,
+			libcni.WithCacheDir(*f.cacheDir), libcni.WithPluginVerifier(verifier))
+
+		err = runAttach(command, cninet, netconf, rt, *f.output)
+		if tracer != nil && *traceFile != "" {
+			if writeErr := tracer.writeFile(*traceFile); writeErr != nil && err == nil {
+				err = writeErr
+			}
+		}
+		return err
+	}
+}
+
+func runAttach(command string, cninet *libcni.CNIConfig, netconf *libcni.NetworkConfigList, rt *libcni.RuntimeConf, output string) error {
+	switch command {
+	case CmdAdd:
+		result, err := cninet.AddNetworkList(context.TODO(), netconf, rt)
+		if err != nil || result == nil {
+			return err
+		}
+		if output == OutputJSON {
+			return printJSON(result)
+		}
+		return result.Print()
+	case CmdCheck:
+		return cninet.CheckNetworkList(context.TODO(), netconf, rt)
+	default:
+		return cninet.DelNetworkList(context.TODO(), netconf, rt)
+	}
+}
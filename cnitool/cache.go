@@ -0,0 +1,215 @@
+// Copyright 2024 CNI authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/containernetworking/cni/libcni"
+	"github.com/containernetworking/cni/pkg/types/create"
+)
+
+// Actions of the cache command
+const (
+	CacheList   = "list"
+	CacheShow   = "show"
+	CacheDelete = "delete"
+)
+
+// cacheEntry is an attachment recorded in the cache
+type cacheEntry struct {
+	Network        string                 `json:"network"`
+	ContainerID    string                 `json:"containerID"`
+	IfName         string                 `json:"ifname"`
+	NetNS          string                 `json:"netns,omitempty"`
+	Stale          bool                   `json:"stale"`
+	CNIArgs        [][2]string            `json:"cniArgs,omitempty"`
+	CapabilityArgs map[string]interface{} `json:"capabilityArgs,omitempty"`
+	Config         json.RawMessage        `json:"config,omitempty"`
+	Result         json.RawMessage        `json:"result,omitempty"`
+}
+
+// cmdCache lists, shows or deletes attachments cached by libcni
+func cmdCache(args []string, output *string) error {
+	if len(args) == 0 {
+		cacheUsage()
+	}
+	action := args[0]
+	if action != CacheList && action != CacheShow && action != CacheDelete {
+		cacheUsage()
+	}
+
+	fs := newFlagSet(CmdCache+" "+action, "", output)
+	cacheDir := fs.String("cache-dir", libcni.CacheDir, "directory libcni caches results in")
+	containerID := fs.String("container", "", "only attachments of this container ID")
+	network := fs.String("network", "", "only attachments to this network")
+	netns := fs.String("netns", "", "only attachments in this network namespace")
+	staleOnly := fs.Bool("stale", false, "only attachments whose network namespace no longer exists")
+	all := fs.Bool("all", false, "allow delete without filters, removing all cached attachments")
+	if err := parseFlags(fs, args[1:], 0, 0, output); err != nil {
+		return err
+	}
+	if *netns != "" {
+		path, err := filepath.Abs(*netns)
+		if err != nil {
+			return err
+		}
+		*netns = path
+	}
+	if action == CacheDelete && *containerID == "" && *network == "" && *netns == "" && !*staleOnly && !*all {
+		return fmt.Errorf("no filter given; use --all to remove all cached attachments")
+	}
+
+	cninet := libcni.NewCNIConfigWithCacheDir(nil, *cacheDir, nil)
+	attachments, err := cninet.GetCachedAttachments(*containerID)
+	if err != nil {
+		if os.IsNotExist(err) {
+			attachments = nil
+		} else {
+			return err
+		}
+	}
+
+	entries := []*cacheEntry{}
+	for _, a := range attachments {
+		if (*network != "" && a.Network != *network) || (*netns != "" && a.NetNS != *netns) {
+			continue
+		}
+		entry := &cacheEntry{
+			Network:        a.Network,
+			ContainerID:    a.ContainerID,
+			IfName:         a.IfName,
+			NetNS:          a.NetNS,
+			Stale:          isStale(a.NetNS),
+			CNIArgs:        a.CniArgs,
+			CapabilityArgs: a.CapabilityArgs,
+		}
+		if *staleOnly && !entry.Stale {
+			continue
+		}
+		if action == CacheShow {
+			if err := entry.load(cninet, a); err != nil {
+				return err
+			}
+		}
+		entries = append(entries, entry)
+	}
+
+	if action == CacheDelete {
+		for _, e := range entries {
+			rt := &libcni.RuntimeConf{ContainerID: e.ContainerID, IfName: e.IfName}
+			if err := cninet.RemoveCachedAttachment(e.Network, rt); err != nil {
+				return err
+			}
+		}
+	}
+
+	if *output == OutputJSON {
+		return printJSON(entries)
+	}
+	switch action {
+	case CacheShow:
+		return printCacheEntries(entries)
+	case CacheDelete:
+		for _, e := range entries {
+			fmt.Printf("deleted %s %s %s\n", e.Network, e.ContainerID, e.IfName)
+		}
+		return nil
+	default:
+		return printCacheTable(entries)
+	}
+}
+
+// load adds the cached config and result of a to e
+func (e *cacheEntry) load(cninet *libcni.CNIConfig, a *libcni.NetworkAttachment) error {
+	e.Config = a.Config
+	cniVersion, err := create.DecodeVersion(a.Config)
+	if err != nil {
+		return err
+	}
+	list := &libcni.NetworkConfigList{Name: a.Network, CNIVersion: cniVersion}
+	rt := &libcni.RuntimeConf{ContainerID: a.ContainerID, IfName: a.IfName}
+	result, err := cninet.GetNetworkListCachedResult(list, rt)
+	if err != nil {
+		return fmt.Errorf("failed to read cached result of %s %s %s: %w", a.Network, a.ContainerID, a.IfName, err)
+	}
+	if result != nil {
+		if e.Result, err = json.Marshal(result); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func printCacheTable(entries []*cacheEntry) error {
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "NETWORK\tCONTAINER\tIFNAME\tNETNS\tSTALE\n")
+	for _, e := range entries {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%t\n", e.Network, e.ContainerID, e.IfName, e.NetNS, e.Stale)
+	}
+	return tw.Flush()
+}
+
+func printCacheEntries(entries []*cacheEntry) error {
+	for i, e := range entries {
+		if i > 0 {
+			fmt.Println()
+		}
+		fmt.Printf("network:     %s\n", e.Network)
+		fmt.Printf("containerID: %s\n", e.ContainerID)
+		fmt.Printf("ifname:      %s\n", e.IfName)
+		if e.Stale {
+			fmt.Printf("netns:       %s (stale)\n", e.NetNS)
+		} else {
+			fmt.Printf("netns:       %s\n", e.NetNS)
+		}
+		if len(e.CNIArgs) > 0 {
+			fmt.Printf("cniArgs:     %v\n", e.CNIArgs)
+		}
+		if len(e.CapabilityArgs) > 0 {
+			capArgs, err := json.Marshal(e.CapabilityArgs)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("capabilityArgs: %s\n", capArgs)
+		}
+		for _, section := range []struct {
+			name string
+			data json.RawMessage
+		}{{"config", e.Config}, {"result", e.Result}} {
+			if len(section.data) == 0 {
+				continue
+			}
+			indented := &bytes.Buffer{}
+			if err := json.Indent(indented, bytes.TrimSpace(section.data), "  ", "    "); err != nil {
+				return err
+			}
+			fmt.Printf("%s:\n  %s\n", section.name, indented)
+		}
+	}
+	return nil
+}
+
+func cacheUsage() {
+	exe := filepath.Base(os.Args[0])
+	fmt.Fprintf(os.Stderr, "usage: %s %s list|show|delete [flags]\n", exe, CmdCache)
+	fmt.Fprintf(os.Stderr, "Run '%s %s list --help' for the flags.\n", exe, CmdCache)
+	os.Exit(ExitFailure)
+}
@@ -15,15 +15,11 @@
 package main
 
 import (
-	"context"
-	"crypto/sha512"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
-
-	"github.com/containernetworking/cni/libcni"
 )
 
 // Protocol parameters are passed to the plugins via OS environment variables.
@@ -36,14 +32,38 @@ const (
 	EnvCNIArgs        = "CNI_ARGS"
 	/*环境变量名称，用于指定接口名称，可以为空*/
 	EnvCNIIfname      = "CNI_IFNAME"
+	EnvPluginManifest = "CNI_PLUGIN_MANIFEST"
 
 	DefaultNetDir = "/etc/cni/net.d"
 
-	CmdAdd   = "add"
-	CmdCheck = "check"
-	CmdDel   = "del"
+	CmdAdd         = "add"
+	CmdCheck       = "check"
+	CmdDel         = "del"
+	CmdGC          = "gc"
+	CmdStatus      = "status"
+	CmdVersion     = "version"
+	CmdValidate    = "validate"
+	CmdCache       = "cache"
+	CmdExplain     = "explain"
+	CmdReplay      = "replay"
+	CmdConformance = "conformance"
 )
 
+// subcommands maps command names to their implementations
+var subcommands = map[string]func(args []string, output *string) error{
+	CmdAdd:         attachCommand(CmdAdd),
+	CmdCheck:       attachCommand(CmdCheck),
+	CmdDel:         attachCommand(CmdDel),
+	CmdGC:          cmdGC,
+	CmdStatus:      cmdStatus,
+	CmdVersion:     cmdVersion,
+	CmdValidate:    cmdValidate,
+	CmdCache:       cmdCache,
+	CmdExplain:     cmdExplain,
+	CmdReplay:      cmdReplay,
+	CmdConformance: cmdConformance,
+}
+
 func parseArgs(args string) ([][2]string, error) {
 	var result [][2]string
 
@@ -73,90 +93,13 @@ func parseArgs(args string) ([][2]string, error) {
           及规范故只调成cni-plugin去完成具体的工作。
 */
 func main() {
-	/*参数必须大于等于4*/
-	if len(os.Args) < 4 {
-		usage()
-	}
-
-	/*利用环境变量确定netconf对应的目录*/
-	netdir := os.Getenv(EnvNetDir)
-	if netdir == "" {
-		/*未提定此环境变量，使用默认路径，/etc/cni/net.d*/
-		netdir = DefaultNetDir
-	}
-	
-	/*取在netdir下相应名称的conflist配置*/
-	netconf, err := libcni.LoadConfList(netdir, os.Args[2])
-	if err != nil {
-		exit(err)
-	}
-
-	/*通过环境变量EnvCapabilityArgs,通过json解析加载capabilityArgs，为map类型*/
-	var capabilityArgs map[string]interface{}
-	capabilityArgsValue := os.Getenv(EnvCapabilityArgs)
-	if len(capabilityArgsValue) > 0 {
-		if err = json.Unmarshal([]byte(capabilityArgsValue), &capabilityArgs); err != nil {
-			exit(err)
-		}
-	}
-
-	/*通过环境变量EnvCNIArgs，加载cniArgs*/
-	var cniArgs [][2]string
-	args := os.Getenv(EnvCNIArgs)
-	if len(args) > 0 {
-		/*解析参数，生成kv map*/
-		cniArgs, err = parseArgs(args)
-		if err != nil {
-			exit(err)
+	if len(os.Args) >= 2 {
+		if cmd, ok := subcommands[os.Args[1]]; ok {
+			output := new(string)
+			exit(cmd(os.Args[2:], output), *output)
 		}
 	}
-
-	/*通过环境变量EnvCNIIfname，尝试确定ifname*/
-	ifName, ok := os.LookupEnv(EnvCNIIfname)
-	if !ok {
-		/*没有指定环境变量，设置接口名为eth0*/
-		ifName = "eth0"
-	}
-
-	/*确定netns路径，例如注释中提到的/var/run/netns/testing*/
-	netns := os.Args[3]
-	netns, err = filepath.Abs(netns)
-	if err != nil {
-		exit(err)
-	}
-
-	// Generate the containerid by hashing the netns path
-	s := sha512.Sum512([]byte(netns))
-	containerID := fmt.Sprintf("cnitool-%x", s[:10]) /*利用netns路径生成container-id*/
-
-	/*自环境变量EnvCNIPATH中获取plugin的查找路径列表，并构造CNIConfig对象*/
-	cninet := libcni.NewCNIConfig(filepath.SplitList(os.Getenv(EnvCNIPath)), nil/*将exec置为空*/)
-
-	/*利用以上参数，构造runtime conf*/
-	rt := &libcni.RuntimeConf{
-		ContainerID:    containerID,
-		NetNS:          netns,
-		IfName:         ifName,
-		Args:           cniArgs,
-		CapabilityArgs: capabilityArgs,
-	}
-
-	switch os.Args[1] {
-	case CmdAdd:
-		/*调用CNIConfig对象的AddNetworkList函数，执行network添加*/
-		result, err := cninet.AddNetworkList(context.TODO(), netconf, rt)
-		if result != nil {
-			_ = result.Print()
-		}
-		exit(err)
-	case CmdCheck:
-		/*network检查*/
-		err := cninet.CheckNetworkList(context.TODO(), netconf, rt)
-		exit(err)
-	case CmdDel:
-		/*network移除*/
-		exit(cninet.DelNetworkList(context.TODO(), netconf, rt))
-	}
+	usage()
 }
 
 func usage() {
@@ -164,16 +107,28 @@ func usage() {
 	exe := filepath.Base(os.Args[0])/*程序名称*/
 
 	fmt.Fprintf(os.Stderr, "%s: Add, check, or remove network interfaces from a network namespace\n", exe)
-	fmt.Fprintf(os.Stderr, "  %s add   <net> <netns>\n", exe)
-	fmt.Fprintf(os.Stderr, "  %s check <net> <netns>\n", exe)
-	fmt.Fprintf(os.Stderr, "  %s del   <net> <netns>\n", exe)
-	os.Exit(1)
+	fmt.Fprintf(os.Stderr, "  %s add   [flags] <net> <netns>\n", exe)
+	fmt.Fprintf(os.Stderr, "  %s check [flags] <net> <netns>\n", exe)
+	fmt.Fprintf(os.Stderr, "  %s del   [flags] <net> [<netns>]\n", exe)
+	fmt.Fprintf(os.Stderr, "  %s gc       [--all] <net> [<containerID>[:<ifname>]...]\n", exe)
+	fmt.Fprintf(os.Stderr, "  %s status   <net>\n", exe)
+	fmt.Fprintf(os.Stderr, "  %s version  <plugin>\n", exe)
+	fmt.Fprintf(os.Stderr, "  %s validate <net>\n", exe)
+	fmt.Fprintf(os.Stderr, "  %s cache    list|show|delete [flags]\n", exe)
+	fmt.Fprintf(os.Stderr, "  %s explain  [flags] add|check|del <net> [<netns>]\n", exe)
+	fmt.Fprintf(os.Stderr, "  %s replay   [flags] <capture>\n", exe)
+	fmt.Fprintf(os.Stderr, "  %s conformance [flags] <plugin>\n", exe)
+	fmt.Fprintf(os.Stderr, "All subcommands take --output text|json.\n")
+	os.Exit(ExitFailure)
 }
 
-func exit(err error) {
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "%s\n", err)
-		os.Exit(1)
+func exit(err error, output string) {
+	if err != nil && !errors.Is(err, errReported) {
+		if output == OutputJSON {
+			_ = printJSON(asCNIError(err))
+		} else {
+			fmt.Fprintf(os.Stderr, "%s\n", err)
+		}
 	}
-	os.Exit(0)
+	os.Exit(exitStatus(err))
 }
@@ -0,0 +1,89 @@
+// Copyright 2024 CNI authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/containernetworking/cni/pkg/conformance"
+	"github.com/containernetworking/cni/pkg/invoke"
+)
+
+// cmdConformance runs the conformance suite against a plugin binary given
+// by path or by name, which is looked up in CNI_PATH
+func cmdConformance(args []string, output *string) error {
+	fs := newFlagSet(CmdConformance, "<plugin>", output)
+	configFile := fs.String("config", "", "file with plugin-specific configuration keys, e.g. ipam")
+	versions := fs.String("versions", "", "comma-separated CNI versions to test (default: all the plugin supports)")
+	netns := fs.String("netns", "", "network namespace to use (default: create one per version)")
+	if err := parseFlags(fs, args, 1, 1, output); err != nil {
+		return err
+	}
+
+	pluginPath := fs.Arg(0)
+	if !strings.ContainsRune(pluginPath, os.PathSeparator) {
+		var err error
+		pluginPath, err = invoke.FindInPath(pluginPath, filepath.SplitList(os.Getenv(EnvCNIPath)))
+		if err != nil {
+			return err
+		}
+	}
+
+	verifier, err := pluginVerifier()
+	if err != nil {
+		return err
+	}
+	suite := &conformance.Suite{
+		PluginPath: pluginPath,
+		Exec:       &invoke.DefaultExec{RawExec: &invoke.RawExec{Verifier: verifier}},
+	}
+	if *configFile != "" {
+		conf, err := os.ReadFile(*configFile)
+		if err != nil {
+			return err
+		}
+		suite.Config = conf
+	}
+	if *versions != "" {
+		suite.Versions = strings.Split(*versions, ",")
+	}
+	if *netns != "" {
+		path, err := filepath.Abs(*netns)
+		if err != nil {
+			return err
+		}
+		suite.Netns = path
+	}
+
+	report, err := suite.Run(context.TODO())
+	if err != nil {
+		return err
+	}
+	if *output == OutputJSON {
+		err = report.WriteJSON(os.Stdout)
+	} else {
+		err = report.WriteText(os.Stdout)
+	}
+	if err != nil {
+		return err
+	}
+	if !report.Passed() {
+		return errReported
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+// Copyright 2024 CNI authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/containernetworking/cni/libcni"
+)
+
+// cmdExplain prints what each plugin of a network would get for add, check
+// or del, without executing any plugin
+func cmdExplain(args []string, output *string) error {
+	fs, f := newAttachFlagSet(CmdExplain, "add|check|del <net> [<netns>]", output)
+	allEnv := fs.Bool("all-env", false, "print the whole plugin environment, not only the CNI_ variables")
+	if err := parseFlags(fs, args, 2, 3, f.output); err != nil {
+		return err
+	}
+
+	command := strings.ToLower(fs.Arg(0))
+	if command != CmdAdd && command != CmdCheck && command != CmdDel {
+		return fmt.Errorf("cannot explain command %q, only %s, %s and %s", fs.Arg(0), CmdAdd, CmdCheck, CmdDel)
+	}
+	netconf, err := loadNetConf(fs.Arg(1))
+	if err != nil {
+		return err
+	}
+	rt, err := f.runtimeConf(command, fs.Args()[2:])
+	if err != nil {
+		return err
+	}
+	cninet := libcni.NewCNIConfigWithCacheDir(filepath.SplitList(os.Getenv(EnvCNIPath)), *f.cacheDir, nil)
+
+	invocations, err := cninet.RenderNetworkList(context.TODO(), netconf, strings.ToUpper(command), rt)
+	if err != nil {
+		return err
+	}
+	for _, inv := range invocations {
+		if !*allEnv {
+			inv.Env = cniEnv(inv.Env)
+		}
+		sort.Strings(inv.Env)
+	}
+
+	if *f.output == OutputJSON {
+		type invocation struct {
+			Index      int                     `json:"index"`
+			Type       string                  `json:"type"`
+			Name       string                  `json:"name,omitempty"`
+			Command    string                  `json:"command"`
+			Path       string                  `json:"path"`
+			Env        []string                `json:"env"`
+			Stdin      json.RawMessage         `json:"stdin"`
+			PrevResult libcni.PrevResultSource `json:"prevResult"`
+		}
+		out := make([]invocation, 0, len(invocations))
+		for _, inv := range invocations {
+			out = append(out, invocation{inv.Index, inv.Type, inv.Name, inv.Command, inv.Path, inv.Env, inv.Stdin, inv.PrevResult})
+		}
+		return printJSON(out)
+	}
+
+	if len(invocations) == 0 {
+		fmt.Printf("network %s runs no plugins for %s\n", netconf.Name, command)
+		return nil
+	}
+	for i, inv := range invocations {
+		if i > 0 {
+			fmt.Println()
+		}
+		if inv.Name != "" {
+			fmt.Printf("plugin %d: %s (%s)\n", inv.Index, inv.Type, inv.Name)
+		} else {
+			fmt.Printf("plugin %d: %s\n", inv.Index, inv.Type)
+		}
+		fmt.Printf("  path:       %s\n", inv.Path)
+		fmt.Printf("  prevResult: %s\n", inv.PrevResult)
+		fmt.Printf("  environment:\n")
+		for _, kv := range inv.Env {
+			fmt.Printf("    %s\n", kv)
+		}
+		stdin := &bytes.Buffer{}
+		if err := json.Indent(stdin, inv.Stdin, "    ", "    "); err != nil {
+			return err
+		}
+		fmt.Printf("  stdin:\n    %s\n", stdin)
+	}
+	return nil
+}
+
+// cniEnv returns the CNI_ variables of env
+func cniEnv(env []string) []string {
+	out := []string{}
+	for _, kv := range env {
+		if strings.HasPrefix(kv, "CNI_") {
+			out = append(out, kv)
+		}
+	}
+	return out
+}
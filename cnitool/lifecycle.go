@@ -0,0 +1,239 @@
+// Copyright 2024 CNI authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/containernetworking/cni/libcni"
+	"github.com/containernetworking/cni/pkg/invoke"
+	"github.com/containernetworking/cni/pkg/types"
+)
+
+// loadNetConf loads the network configuration list with the given name
+// from NETCONFPATH
+func loadNetConf(name string) (*libcni.NetworkConfigList, error) {
+	netdir := os.Getenv(EnvNetDir)
+	if netdir == "" {
+		netdir = DefaultNetDir
+	}
+	return libcni.LoadConfList(netdir, name)
+}
+
+// newCNIConfig returns a CNIConfig finding plugins in CNI_PATH and
+// verifying them against CNI_PLUGIN_MANIFEST, if set
+func newCNIConfig() (*libcni.CNIConfig, error) {
+	verifier, err := pluginVerifier()
+	if err != nil {
+		return nil, err
+	}
+	return libcni.NewCNIConfigWithOptions(filepath.SplitList(os.Getenv(EnvCNIPath)), nil, libcni.WithPluginVerifier(verifier)), nil
+}
+
+// pluginVerifier returns a verifier for the plugin manifest named by
+// CNI_PLUGIN_MANIFEST, or nil if it is not set
+func pluginVerifier() (*invoke.PluginVerifier, error) {
+	path := os.Getenv(EnvPluginManifest)
+	if path == "" {
+		return nil, nil
+	}
+	digests, err := invoke.LoadPluginManifest(path)
+	if err != nil {
+		return nil, err
+	}
+	return invoke.NewPluginVerifier(digests), nil
+}
+
+func defaultIfName() string {
+	if ifName, ok := os.LookupEnv(EnvCNIIfname); ok {
+		return ifName
+	}
+	return "eth0"
+}
+
+// parseAttachment parses a valid attachment given as <containerID>[:<ifname>]
+func parseAttachment(s string) (libcni.GCAttachment, error) {
+	containerID, ifName, _ := strings.Cut(s, ":")
+	if containerID == "" {
+		return libcni.GCAttachment{}, fmt.Errorf("invalid attachment %q", s)
+	}
+	if ifName == "" {
+		ifName = defaultIfName()
+	}
+	return libcni.GCAttachment{ContainerID: containerID, IfName: ifName}, nil
+}
+
+// cmdGC deletes all attachments of a network except the given ones and
+// asks its plugins to clean up
+func cmdGC(args []string, output *string) error {
+	fs := newFlagSet(CmdGC, "<net> [<containerID>[:<ifname>]...]", output)
+	all := fs.Bool("all", false, "allow GC without valid attachments, removing all attachments of the network")
+	if err := parseFlags(fs, args, 1, -1, output); err != nil {
+		return err
+	}
+
+	gcArgs := &libcni.GCArgs{ValidAttachments: []libcni.GCAttachment{}}
+	for _, s := range fs.Args()[1:] {
+		a, err := parseAttachment(s)
+		if err != nil {
+			return err
+		}
+		gcArgs.ValidAttachments = append(gcArgs.ValidAttachments, a)
+	}
+	if len(gcArgs.ValidAttachments) == 0 && !*all {
+		return fmt.Errorf("no valid attachments given; use --all to remove all attachments of the network")
+	}
+
+	netconf, err := loadNetConf(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	cninet, err := newCNIConfig()
+	if err != nil {
+		return err
+	}
+	if err := cninet.GCNetworkList(context.TODO(), netconf, gcArgs); err != nil {
+		return err
+	}
+
+	if *output == OutputJSON {
+		return printJSON(struct {
+			Network          string                `json:"network"`
+			ValidAttachments []libcni.GCAttachment `json:"validAttachments"`
+		}{netconf.Name, gcArgs.ValidAttachments})
+	}
+	fmt.Printf("garbage collected network %s, keeping %d attachment(s)\n", netconf.Name, len(gcArgs.ValidAttachments))
+	return nil
+}
+
+// cmdStatus reports whether a network's plugins are ready for ADDs
+func cmdStatus(args []string, output *string) error {
+	fs := newFlagSet(CmdStatus, "<net>", output)
+	if err := parseFlags(fs, args, 1, 1, output); err != nil {
+		return err
+	}
+
+	netconf, err := loadNetConf(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	cninet, err := newCNIConfig()
+	if err != nil {
+		return err
+	}
+	statusErr := cninet.GetStatusNetworkList(context.TODO(), netconf)
+
+	if *output == OutputJSON {
+		status := struct {
+			Network string       `json:"network"`
+			Ready   bool         `json:"ready"`
+			Error   *types.Error `json:"error,omitempty"`
+		}{Network: netconf.Name, Ready: statusErr == nil}
+		if statusErr != nil {
+			status.Error = asCNIError(statusErr)
+		}
+		if err := printJSON(status); err != nil {
+			return err
+		}
+	} else if statusErr == nil {
+		fmt.Printf("network %s is ready\n", netconf.Name)
+	} else {
+		fmt.Printf("network %s is not ready: %v\n", netconf.Name, statusErr)
+	}
+	if statusErr != nil {
+		return fmt.Errorf("%w: %w", errReported, statusErr)
+	}
+	return nil
+}
+
+// cmdVersion prints the CNI versions a plugin supports
+func cmdVersion(args []string, output *string) error {
+	fs := newFlagSet(CmdVersion, "<plugin>", output)
+	if err := parseFlags(fs, args, 1, 1, output); err != nil {
+		return err
+	}
+
+	cninet, err := newCNIConfig()
+	if err != nil {
+		return err
+	}
+	info, err := cninet.GetVersionInfo(context.TODO(), fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	if *output == OutputJSON {
+		return printJSON(struct {
+			Plugin            string   `json:"plugin"`
+			SupportedVersions []string `json:"supportedVersions"`
+		}{fs.Arg(0), info.SupportedVersions()})
+	}
+	fmt.Printf("plugin %s supports CNI versions: %s\n", fs.Arg(0), strings.Join(info.SupportedVersions(), ", "))
+	return nil
+}
+
+// cmdValidate checks that a network's plugins exist and support its
+// version, and prints the capabilities it supports
+func cmdValidate(args []string, output *string) error {
+	fs := newFlagSet(CmdValidate, "<net>", output)
+	if err := parseFlags(fs, args, 1, 1, output); err != nil {
+		return err
+	}
+
+	netconf, err := loadNetConf(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	cninet, err := newCNIConfig()
+	if err != nil {
+		return err
+	}
+	caps, validateErr := cninet.ValidateNetworkList(context.TODO(), netconf)
+	sort.Strings(caps)
+
+	if *output == OutputJSON {
+		validation := struct {
+			Network      string       `json:"network"`
+			CNIVersion   string       `json:"cniVersion"`
+			Valid        bool         `json:"valid"`
+			Capabilities []string     `json:"capabilities"`
+			Error        *types.Error `json:"error,omitempty"`
+		}{Network: netconf.Name, CNIVersion: netconf.CNIVersion, Valid: validateErr == nil, Capabilities: caps}
+		if validation.Capabilities == nil {
+			validation.Capabilities = []string{}
+		}
+		if validateErr != nil {
+			validation.Error = asCNIError(validateErr)
+		}
+		if err := printJSON(validation); err != nil {
+			return err
+		}
+	} else if validateErr == nil {
+		fmt.Printf("network %s (CNI version %s) is valid\n", netconf.Name, netconf.CNIVersion)
+		if len(caps) > 0 {
+			fmt.Printf("capabilities: %s\n", strings.Join(caps, ", "))
+		}
+	} else {
+		fmt.Printf("network %s is invalid: %v\n", netconf.Name, validateErr)
+	}
+	if validateErr != nil {
+		return fmt.Errorf("%w: %w", errReported, validateErr)
+	}
+	return nil
+}
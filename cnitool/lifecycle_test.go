@@ -0,0 +1,60 @@
+// Copyright 2024 CNI authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/containernetworking/cni/libcni"
+)
+
+var _ = Describe("parseAttachment", func() {
+	BeforeEach(func() {
+		GinkgoT().Setenv(EnvCNIIfname, "net0")
+	})
+
+	It("parses a container ID and interface name", func() {
+		Expect(parseAttachment("abc:eth1")).To(Equal(libcni.GCAttachment{ContainerID: "abc", IfName: "eth1"}))
+	})
+
+	It("defaults the interface name to CNI_IFNAME", func() {
+		Expect(parseAttachment("abc")).To(Equal(libcni.GCAttachment{ContainerID: "abc", IfName: "net0"}))
+	})
+
+	It("rejects a missing container ID", func() {
+		_, err := parseAttachment(":eth0")
+		Expect(err).To(MatchError(`invalid attachment ":eth0"`))
+	})
+})
+
+var _ = Describe("isStale", func() {
+	It("treats attachments without a netns as live", func() {
+		Expect(isStale("")).To(BeFalse())
+	})
+
+	It("detects a missing netns", func() {
+		Expect(isStale(filepath.Join(GinkgoT().TempDir(), "gone"))).To(BeTrue())
+	})
+
+	It("detects a path that is not a network namespace", func() {
+		path := filepath.Join(GinkgoT().TempDir(), "file")
+		Expect(os.WriteFile(path, nil, 0o600)).To(Succeed())
+		Expect(isStale(path)).To(BeTrue())
+	})
+})
@@ -0,0 +1,113 @@
+// Copyright 2024 CNI authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/containernetworking/cni/pkg/types"
+)
+
+// Output formats selected with --output
+const (
+	OutputText = "text"
+	OutputJSON = "json"
+)
+
+// Exit statuses. Failures with one of the well-known CNI error codes, 1 to
+// 99, exit with that code.
+const (
+	// ExitPluginError is the status of plugin-specific CNI error codes,
+	// 100 and above, apart from ErrInternal
+	ExitPluginError = 100
+	// ExitFailure is the status of failures with code ErrUnknown or
+	// ErrInternal, which includes failures not reported by a plugin, and
+	// of usage errors
+	ExitFailure = 125
+)
+
+// errReported is returned by commands that failed after printing why, so
+// that only the exit status is left to set. It may wrap the error that
+// determines the exit status.
+var errReported = errors.New("failure already reported")
+
+// newFlagSet returns a flag set for a subcommand, with usage showing the
+// positional arguments, which stores its --output flag in output so that
+// the command's error can be printed in that format
+func newFlagSet(command, positional string, output *string) *flag.FlagSet {
+	fs := flag.NewFlagSet(command, flag.ContinueOnError)
+	fs.StringVar(output, "output", OutputText, "output format: text or json")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: %s %s [flags] %s\n", filepath.Base(os.Args[0]), command, positional)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parseFlags parses args and checks the number of positional arguments and
+// the output format
+func parseFlags(fs *flag.FlagSet, args []string, minArgs, maxArgs int, output *string) error {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(0)
+		}
+		os.Exit(ExitFailure)
+	}
+	if fs.NArg() < minArgs || (maxArgs >= 0 && fs.NArg() > maxArgs) {
+		fs.Usage()
+		os.Exit(ExitFailure)
+	}
+	if *output != OutputText && *output != OutputJSON {
+		return fmt.Errorf("invalid output format %q", *output)
+	}
+	return nil
+}
+
+func printJSON(v interface{}) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "    ")
+	return enc.Encode(v)
+}
+
+// exitStatus returns the exit status for err, derived from the code of
+// asCNIError(err) so that it agrees with the error printed as JSON
+func exitStatus(err error) int {
+	if err == nil {
+		return 0
+	}
+	switch code := asCNIError(err).Code; {
+	case code == types.ErrUnknown || code == types.ErrInternal:
+		return ExitFailure
+	case code >= 100:
+		return ExitPluginError
+	default:
+		return int(code)
+	}
+}
+
+// asCNIError returns the CNI error carried by err, or wraps err in one with
+// code ErrInternal
+func asCNIError(err error) *types.Error {
+	var e *types.Error
+	if errors.As(err, &e) {
+		return e
+	}
+	return types.NewError(types.ErrInternal, err.Error(), "")
+}
@@ -0,0 +1,67 @@
+// Copyright 2024 CNI authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/containernetworking/cni/pkg/invoke"
+	"github.com/containernetworking/cni/pkg/types"
+)
+
+var _ = Describe("exit statuses", func() {
+	DescribeTable("are derived from the CNI error code",
+		func(err error, status int) {
+			Expect(exitStatus(err)).To(Equal(status))
+		},
+		Entry("success", nil, 0),
+		Entry("a failure not reported by a plugin", errors.New("no such network"), ExitFailure),
+		Entry("ErrUnknown", types.NewError(types.ErrUnknown, "unknown", ""), ExitFailure),
+		Entry("ErrInternal", types.NewError(types.ErrInternal, "internal", ""), ExitFailure),
+		Entry("a well-known code", types.NewError(types.ErrTryAgainLater, "busy", ""), int(types.ErrTryAgainLater)),
+		Entry("a plugin-specific code", types.NewError(117, "banana", ""), ExitPluginError),
+		Entry("a wrapped plugin error",
+			fmt.Errorf("plugin failed: %w", &invoke.ExecError{Err: types.NewError(types.ErrIncompatibleCNIVersion, "incompatible", ""), ExitStatus: 1}),
+			int(types.ErrIncompatibleCNIVersion)),
+		Entry("an already reported failure", errReported, ExitFailure),
+		Entry("an already reported plugin error",
+			fmt.Errorf("%w: %w", errReported, types.NewError(types.ErrTryAgainLater, "busy", "")),
+			int(types.ErrTryAgainLater)),
+	)
+
+	DescribeTable("agree with the error printed as JSON",
+		func(err error) {
+			out, marshalErr := json.Marshal(asCNIError(err))
+			Expect(marshalErr).NotTo(HaveOccurred())
+			printed := &types.Error{}
+			Expect(json.Unmarshal(out, printed)).To(Succeed())
+			Expect(exitStatus(printed)).To(Equal(exitStatus(err)))
+		},
+		Entry("a failure not reported by a plugin", errors.New("no such network")),
+		Entry("a well-known code", fmt.Errorf("failed: %w", types.NewError(types.ErrTryAgainLater, "busy", ""))),
+		Entry("a plugin-specific code", types.NewError(117, "banana", "details")),
+	)
+
+	It("prints failures not reported by a plugin as ErrInternal", func() {
+		e := asCNIError(errors.New("no such network"))
+		Expect(e.Code).To(Equal(uint(types.ErrInternal)))
+		Expect(e.Msg).To(Equal("no such network"))
+	})
+})
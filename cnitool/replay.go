@@ -0,0 +1,207 @@
+// Copyright 2024 CNI authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/containernetworking/cni/pkg/invoke"
+)
+
+// replayResult compares a recorded plugin execution with its replay
+type replayResult struct {
+	Index    int               `json:"index"`
+	Plugin   string            `json:"plugin"`
+	Command  string            `json:"command"`
+	Same     bool              `json:"same"`
+	Diff     string            `json:"diff,omitempty"`
+	Replayed *invoke.Recording `json:"replayed"`
+}
+
+// cmdReplay re-runs the plugin executions of a capture file written with
+// --record and reports how the outputs differ
+func cmdReplay(args []string, output *string) error {
+	fs := newFlagSet(CmdReplay, "<capture>", output)
+	plugin := fs.String("plugin", "", "plugin binary to run (default: the recorded plugin's name, looked up in CNI_PATH)")
+	index := fs.Int("index", -1, "only replay the recording with this index")
+	if err := parseFlags(fs, args, 1, 1, output); err != nil {
+		return err
+	}
+
+	if *index < -1 {
+		return fmt.Errorf("invalid --index %d", *index)
+	}
+
+	recordings, err := invoke.ReadRecordingsFile(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	if *index >= len(recordings) {
+		return fmt.Errorf("no recording %d in %s, which has %d", *index, fs.Arg(0), len(recordings))
+	}
+
+	verifier, err := pluginVerifier()
+	if err != nil {
+		return err
+	}
+
+	results := []*replayResult{}
+	for i, rec := range recordings {
+		if *index >= 0 && i != *index {
+			continue
+		}
+		// Capture files may come from elsewhere, so never run the path
+		// they name, only the plugin of the same name in CNI_PATH
+		pluginPath := *plugin
+		if pluginPath == "" {
+			pluginPath, err = invoke.FindInPath(filepath.Base(rec.Plugin), filepath.SplitList(os.Getenv(EnvCNIPath)))
+			if err != nil {
+				return err
+			}
+		}
+		replayed, err := replay(pluginPath, verifier, rec)
+		if err != nil {
+			return err
+		}
+		diff := diffLines(outputLines(rec), outputLines(replayed))
+		results = append(results, &replayResult{
+			Index:    i,
+			Plugin:   pluginPath,
+			Command:  rec.Command(),
+			Same:     diff == "",
+			Diff:     diff,
+			Replayed: replayed,
+		})
+	}
+
+	same := true
+	for _, res := range results {
+		same = same && res.Same
+	}
+	if *output == OutputJSON {
+		if err := printJSON(results); err != nil {
+			return err
+		}
+	} else {
+		for _, res := range results {
+			rec := recordings[res.Index]
+			status := "same"
+			if !res.Same {
+				status = "differs"
+			}
+			fmt.Printf("recording %d: %s %s: %s (took %s, recorded %s)\n", res.Index, res.Command, res.Plugin, status, res.Replayed.Duration, rec.Duration)
+			if !res.Same {
+				fmt.Printf("  --- recorded\n  +++ replayed\n")
+				for _, line := range strings.Split(strings.TrimSuffix(res.Diff, "\n"), "\n") {
+					fmt.Printf("  %s\n", line)
+				}
+			}
+		}
+	}
+	if !same {
+		return errReported
+	}
+	return nil
+}
+
+// replay executes pluginPath with the stdin and environment of rec and
+// records the execution
+func replay(pluginPath string, verifier *invoke.PluginVerifier, rec *invoke.Recording) (*invoke.Recording, error) {
+	capture := &bytes.Buffer{}
+	recorder := &invoke.Recorder{Output: capture, Verifier: verifier}
+	_, _ = recorder.ExecPlugin(context.TODO(), pluginPath, rec.StdinData(), rec.Env)
+	replayed, err := invoke.ReadRecordings(capture)
+	if err != nil {
+		return nil, err
+	}
+	if len(replayed) != 1 {
+		return nil, fmt.Errorf("failed to record the replay of %s", pluginPath)
+	}
+	return replayed[0], nil
+}
+
+// outputLines returns the outputs of a plugin execution to compare, with
+// JSON indented so that differences show line by line
+func outputLines(rec *invoke.Recording) []string {
+	lines := []string{fmt.Sprintf("exit status: %d", rec.ExitStatus)}
+	if rec.Error != nil {
+		e, _ := json.Marshal(rec.Error)
+		lines = append(lines, "error: "+string(e))
+	}
+	if len(rec.Stdout) > 0 {
+		stdout := &bytes.Buffer{}
+		if err := json.Indent(stdout, rec.Stdout, "", "  "); err != nil {
+			stdout.Reset()
+			stdout.Write(rec.Stdout)
+		}
+		lines = append(lines, "stdout:")
+		lines = append(lines, strings.Split(stdout.String(), "\n")...)
+	}
+	if rec.Stderr != "" {
+		lines = append(lines, "stderr:")
+		lines = append(lines, strings.Split(strings.TrimSuffix(rec.Stderr, "\n"), "\n")...)
+	}
+	return lines
+}
+
+// diffLines returns the lines of a and b as a diff, with removed lines
+// prefixed by "-" and added lines by "+", or "" if they are equal
+func diffLines(a, b []string) string {
+	// lcs[i][j] is the length of the longest common subsequence of a[i:]
+	// and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	out := &strings.Builder{}
+	changed := false
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			fmt.Fprintf(out, " %s\n", a[i])
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			fmt.Fprintf(out, "-%s\n", a[i])
+			changed = true
+			i++
+		default:
+			fmt.Fprintf(out, "+%s\n", b[j])
+			changed = true
+			j++
+		}
+	}
+	if !changed {
+		return ""
+	}
+	return out.String()
+}
@@ -0,0 +1,71 @@
+// Copyright 2024 CNI authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/containernetworking/cni/pkg/invoke"
+	"github.com/containernetworking/cni/pkg/types"
+)
+
+var _ = Describe("diffLines", func() {
+	DescribeTable("diffs lines by their longest common subsequence",
+		func(a, b []string, diff string) {
+			Expect(diffLines(a, b)).To(Equal(diff))
+		},
+		Entry("equal lines", []string{"a", "b"}, []string{"a", "b"}, ""),
+		Entry("no lines", nil, nil, ""),
+		Entry("an added line", []string{"a", "c"}, []string{"a", "b", "c"}, " a\n+b\n c\n"),
+		Entry("a removed line", []string{"a", "b", "c"}, []string{"a", "c"}, " a\n-b\n c\n"),
+		Entry("a changed line", []string{"a", "b", "c"}, []string{"a", "x", "c"}, " a\n-b\n+x\n c\n"),
+		Entry("all lines removed", []string{"a", "b"}, nil, "-a\n-b\n"),
+		Entry("all lines added", nil, []string{"a"}, "+a\n"),
+		Entry("a moved line", []string{"a", "b", "c"}, []string{"b", "c", "a"}, "-a\n b\n c\n+a\n"),
+	)
+})
+
+var _ = Describe("outputLines", func() {
+	It("lists the exit status, error, indented stdout and stderr", func() {
+		rec := &invoke.Recording{
+			ExitStatus: 1,
+			Error:      types.NewError(types.ErrTryAgainLater, "busy", ""),
+			Stdout:     json.RawMessage(`{"code":11,"msg":"busy"}`),
+			Stderr:     "first\nsecond\n",
+		}
+		Expect(outputLines(rec)).To(Equal([]string{
+			"exit status: 1",
+			`error: {"code":11,"msg":"busy"}`,
+			"stdout:",
+			"{",
+			`  "code": 11,`,
+			`  "msg": "busy"`,
+			"}",
+			"stderr:",
+			"first",
+			"second",
+		}))
+	})
+
+	It("differs only in the lines that changed", func() {
+		recorded := &invoke.Recording{Stdout: json.RawMessage(`{"cniVersion":"1.0.0","ips":[]}`)}
+		replayed := &invoke.Recording{Stdout: json.RawMessage(`{"cniVersion":"1.1.0","ips":[]}`)}
+		Expect(diffLines(outputLines(recorded), outputLines(replayed))).To(Equal(
+			" exit status: 0\n stdout:\n {\n-  \"cniVersion\": \"1.0.0\",\n+  \"cniVersion\": \"1.1.0\",\n   \"ips\": []\n }\n"))
+	})
+})
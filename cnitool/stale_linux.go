@@ -0,0 +1,33 @@
+// Copyright 2024 CNI authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+
+	"github.com/containernetworking/cni/pkg/ns"
+)
+
+// isStale returns true if the attachment's network namespace is gone, i.e.
+// its path does not exist or is no longer a network namespace. The netns of
+// attachments cached by a DEL without netns is unknown, so they are never
+// stale.
+func isStale(netns string) bool {
+	if netns == "" {
+		return false
+	}
+	err := ns.IsNSorErr(netns)
+	return errors.As(err, &ns.NSPathNotExistErr{}) || errors.As(err, &ns.NSPathNotNSErr{})
+}
@@ -0,0 +1,151 @@
+// Copyright 2024 CNI authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/containernetworking/cni/pkg/invoke"
+	"github.com/containernetworking/cni/pkg/types"
+	"github.com/containernetworking/cni/pkg/version"
+)
+
+// traceHop is one plugin execution
+type traceHop struct {
+	Plugin     string          `json:"plugin"`
+	Command    string          `json:"command"`
+	Stdin      json.RawMessage `json:"stdin"`
+	Stdout     interface{}     `json:"stdout,omitempty"`
+	Stderr     string          `json:"stderr,omitempty"`
+	ExitStatus int             `json:"exitStatus"`
+	Duration   string          `json:"duration"`
+	Error      *types.Error    `json:"error,omitempty"`
+}
+
+// tracingExec executes plugins and records each execution, printing it to
+// out if set
+type tracingExec struct {
+	version.PluginDecoder
+	out      io.Writer
+	verifier *invoke.PluginVerifier
+	hops     []*traceHop
+}
+
+func (e *tracingExec) ExecPlugin(ctx context.Context, pluginPath string, stdinData []byte, environ []string) ([]byte, error) {
+	stderr := &bytes.Buffer{}
+	raw := &invoke.RawExec{Stderr: stderr, Verifier: e.verifier}
+	start := time.Now()
+	stdout, err := raw.ExecPlugin(ctx, pluginPath, stdinData, environ)
+	duration := time.Since(start)
+
+	hop := &traceHop{
+		Plugin:   pluginPath,
+		Command:  envValue(environ, "CNI_COMMAND"),
+		Stdin:    stdinData,
+		Duration: duration.String(),
+	}
+	if len(stdout) > 0 {
+		hop.Stdout = jsonOrString(stdout)
+	}
+	if err != nil {
+		hop.Error = asCNIError(err)
+		hop.ExitStatus = -1
+		var execErr *invoke.ExecError
+		if errors.As(err, &execErr) {
+			hop.ExitStatus = execErr.ExitStatus
+			stderr.Write(execErr.Stderr)
+		}
+	}
+	hop.Stderr = stderr.String()
+	e.hops = append(e.hops, hop)
+
+	if e.out != nil {
+		e.print(len(e.hops), hop)
+	} else if err == nil {
+		// Without a printed trace, stderr goes where it usually does
+		_, _ = stderr.WriteTo(os.Stderr)
+	}
+	return stdout, err
+}
+
+func (e *tracingExec) FindInPath(plugin string, paths []string) (string, error) {
+	return invoke.FindInPath(plugin, paths)
+}
+
+func (e *tracingExec) print(n int, hop *traceHop) {
+	fmt.Fprintf(e.out, "--- plugin %d: %s %s\n", n, hop.Command, hop.Plugin)
+	printTraceSection(e.out, "stdin", hop.Stdin)
+	if hop.Stdout != nil {
+		stdout, _ := json.Marshal(hop.Stdout)
+		printTraceSection(e.out, "stdout", stdout)
+	}
+	if hop.Stderr != "" {
+		fmt.Fprintf(e.out, "stderr:\n")
+		for _, line := range strings.Split(strings.TrimRight(hop.Stderr, "\n"), "\n") {
+			fmt.Fprintf(e.out, "  %s\n", line)
+		}
+	}
+	if hop.Error != nil {
+		fmt.Fprintf(e.out, "error: %v (code %d)\n", hop.Error, hop.Error.Code)
+	}
+	fmt.Fprintf(e.out, "exit status %d after %s\n", hop.ExitStatus, hop.Duration)
+}
+
+func printTraceSection(w io.Writer, name string, data []byte) {
+	indented := &bytes.Buffer{}
+	if err := json.Indent(indented, bytes.TrimSpace(data), "  ", "  "); err != nil {
+		indented.Reset()
+		indented.Write(data)
+	}
+	fmt.Fprintf(w, "%s:\n  %s\n", name, indented)
+}
+
+// writeFile writes the trace as JSON to path
+func (e *tracingExec) writeFile(path string) error {
+	data, err := json.MarshalIndent(struct {
+		Hops []*traceHop `json:"hops"`
+	}{e.hops}, "", "    ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// jsonOrString returns data as raw JSON if it is valid JSON, so that it is
+// embedded as is in the trace file, or else as a string
+func jsonOrString(data []byte) interface{} {
+	if json.Valid(data) {
+		return json.RawMessage(data)
+	}
+	return string(data)
+}
+
+// envValue returns the value of key in environ
+func envValue(environ []string, key string) string {
+	for i := len(environ) - 1; i >= 0; i-- {
+		if k, v, ok := strings.Cut(environ[i], "="); ok && k == key {
+			return v
+		}
+	}
+	return ""
+}
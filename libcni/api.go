@@ -24,10 +24,12 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/containernetworking/cni/pkg/invoke"
 	"github.com/containernetworking/cni/pkg/types"
@@ -136,11 +138,68 @@ type CNIConfig struct {
 	/*用于执行插件的辅助对象*/
 	exec     invoke.Exec
 	cacheDir string
+	logger   *slog.Logger
+	verifier *invoke.PluginVerifier
+
+	validateResults bool
 }
 
 // CNIConfig implements the CNI interface
 var _ CNI = &CNIConfig{}
 
+// Option configures optional behavior of a CNIConfig
+type Option func(c *CNIConfig)
+
+// WithLogger makes the default exec handler emit each line plugins write
+// to stderr as a record on logger, annotated with the plugin type, CNI
+// command, container ID and interface name. It has no effect when the
+// CNIConfig is given its own exec interface.
+func WithLogger(logger *slog.Logger) Option {
+	return func(c *CNIConfig) {
+		c.logger = logger
+	}
+}
+
+// WithCacheDir makes the CNIConfig cache results and attachments in dir
+// instead of /var/lib/cni
+func WithCacheDir(dir string) Option {
+	return func(c *CNIConfig) {
+		c.cacheDir = dir
+	}
+}
+
+// WithPluginVerifier makes the default exec handler refuse to execute
+// plugin binaries that verifier rejects. It has no effect when the
+// CNIConfig is given its own exec interface.
+func WithPluginVerifier(verifier *invoke.PluginVerifier) Option {
+	return func(c *CNIConfig) {
+		c.verifier = verifier
+	}
+}
+
+// WithResultValidation makes ADD operations check each plugin's result
+// for semantic errors, such as IP configurations referring to missing
+// interfaces or gateways in the wrong address family. An invalid result
+// fails the operation with a types.ErrDecodingFailure error describing the
+// problems, before it is cached or passed to the next plugin.
+func WithResultValidation() Option {
+	return func(c *CNIConfig) {
+		c.validateResults = true
+	}
+}
+
+// NewCNIConfigWithOptions returns a new CNIConfig object that will search
+// for plugins in the given paths and use the given exec interface to run
+// those plugins, or a default exec handler if exec is nil, configured by
+// the given options.
+func NewCNIConfigWithOptions(path []string, exec invoke.Exec, opts ...Option) *CNIConfig {
+	c := NewCNIConfig(path, exec)
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
 // NewCNIConfig returns a new CNIConfig object that will search for plugins
 // in the given paths and use the given exec interface to run those plugins,
 // or if the exec interface is not given, will use a default exec handler.
@@ -235,7 +294,7 @@ func (c *CNIConfig) ensureExec() invoke.Exec {
 		/*当前未初始化，这里构造为invoke.DefaultExec对象*/
 		c.exec = &invoke.DefaultExec{
 			/*初始化为rawExec,指明标准错误输出*/
-			RawExec:       &invoke.RawExec{Stderr: os.Stderr},
+			RawExec:       &invoke.RawExec{Stderr: os.Stderr, Logger: c.logger, Verifier: c.verifier},
 			/*指定插件解码对象构造为version.PluginDecoder*/
 			PluginDecoder: version.PluginDecoder{},
 		}
@@ -317,15 +376,6 @@ func (c *CNIConfig) cacheAdd(result types.Result, config []byte, netName string,
 	return os.WriteFile(fname, newBytes, 0o600)
 }
 
-func (c *CNIConfig) cacheDel(netName string, rt *RuntimeConf) error {
-	fname, err := c.getCacheFilePath(netName, rt)
-	if err != nil {
-		// Ignore error
-		return nil
-	}
-	return os.Remove(fname)
-}
-
 func (c *CNIConfig) getCachedConfig(netName string, rt *RuntimeConf) ([]byte, *RuntimeConf, error) {
 	var bytes []byte
 
@@ -505,6 +555,18 @@ func (c *CNIConfig) GetCachedAttachments(containerID string) ([]*NetworkAttachme
 	return attachments, nil
 }
 
+// RemoveCachedAttachment removes the cached result and config of an
+// attachment, e.g. one whose container is gone, without executing any
+// plugin. It returns an error wrapping os.ErrNotExist if the attachment is
+// not cached.
+func (c *CNIConfig) RemoveCachedAttachment(netName string, rt *RuntimeConf) error {
+	fname, err := c.getCacheFilePath(netName, rt)
+	if err != nil {
+		return err
+	}
+	return os.Remove(fname)
+}
+
 /*添加network*/
 func (c *CNIConfig) addNetwork(ctx context.Context, name/*network名称*/, cniVersion string, net *NetworkConfig/*network配置*/, prevResult types.Result, rt *RuntimeConf) (types.Result, error) {
 	/*防止c.exec未初始化*/
@@ -513,7 +575,7 @@ func (c *CNIConfig) addNetwork(ctx context.Context, name/*network名称*/, cniVe
 	/*查询并获取插件执行路径*/
 	pluginPath, err := c.exec.FindInPath(net.Network.Type/*此network类型为插件名称*/, c.Path)
 	if err != nil {
-		return nil, err
+		return nil, newPluginError(net.Network, "ADD", err, 0)
 	}
 	
 	/*检查containerid是否合乎约定*/
@@ -538,7 +600,31 @@ func (c *CNIConfig) addNetwork(ctx context.Context, name/*network名称*/, cniVe
 	}
 
 	/*运行插件并返回运行结果，可参见各cniVersion对应的Result结构体*/
-	return invoke.ExecPluginWithResult(ctx, pluginPath/*插件路径*/, newConf.Bytes/*配置内容*/, c.args("ADD", rt)/*将rt打包成配置参数*/, c.exec)
+	start := time.Now()
+	result, err := invoke.ExecPluginWithResult(ctx, pluginPath/*插件路径*/, newConf.Bytes/*配置内容*/, c.args("ADD", rt)/*将rt打包成配置参数*/, c.exec)
+	if err != nil {
+		return nil, newPluginError(net.Network, "ADD", err, time.Since(start))
+	}
+	if c.validateResults {
+		if err := validateResult(result); err != nil {
+			return nil, newPluginError(net.Network, "ADD", err, time.Since(start))
+		}
+	}
+	return result, nil
+}
+
+// validateResult returns a types.Error describing any semantic problems
+// with a plugin's result
+func validateResult(result types.Result) *types.Error {
+	v, ok := result.(interface{ Validate() error })
+	if !ok {
+		return nil
+	}
+	if err := v.Validate(); err != nil {
+		details := strings.ReplaceAll(err.Error(), "\n", "; ")
+		return types.NewError(types.ErrDecodingFailure, "plugin returned an invalid result", details)
+	}
+	return nil
 }
 
 // AddNetworkList executes a sequence of plugins with the ADD command
@@ -546,10 +632,10 @@ func (c *CNIConfig) AddNetworkList(ctx context.Context, list *NetworkConfigList,
 	var err error
 	var result types.Result
 	/*遍历此conflist中的所有NetworkConfig，逐个添加，如有一个失败者，则返回*/
-	for _, net := range list.Plugins {
+	for i, net := range list.Plugins {
 		result, err = c.addNetwork(ctx, list.Name, list.CNIVersion, net, result/*上一个配置为空*/, rt)
 		if err != nil {
-			return nil, fmt.Errorf("plugin %s failed (add): %w", pluginDescription(net.Network), err)
+			return nil, fmt.Errorf("plugin %s failed (add): %w", pluginDescription(net.Network), withPluginIndex(err, i))
 		}
 	}
 
@@ -566,7 +652,7 @@ func (c *CNIConfig) checkNetwork(ctx context.Context, name, cniVersion string, n
 	c.ensureExec()
 	pluginPath, err := c.exec.FindInPath(net.Network.Type, c.Path)
 	if err != nil {
-		return err
+		return newPluginError(net.Network, "CHECK", err, 0)
 	}
 
 	newConf, err := buildOneConfig(name, cniVersion, net, prevResult, rt)
@@ -574,7 +660,11 @@ func (c *CNIConfig) checkNetwork(ctx context.Context, name, cniVersion string, n
 		return err
 	}
 
-	return invoke.ExecPluginWithoutResult(ctx, pluginPath, newConf.Bytes, c.args("CHECK", rt), c.exec)
+	start := time.Now()
+	if err := invoke.ExecPluginWithoutResult(ctx, pluginPath, newConf.Bytes, c.args("CHECK", rt), c.exec); err != nil {
+		return newPluginError(net.Network, "CHECK", err, time.Since(start))
+	}
+	return nil
 }
 
 // CheckNetworkList executes a sequence of plugins with the CHECK command
@@ -595,9 +685,9 @@ func (c *CNIConfig) CheckNetworkList(ctx context.Context, list *NetworkConfigLis
 		return fmt.Errorf("failed to get network %q cached result: %w", list.Name, err)
 	}
 
-	for _, net := range list.Plugins {
+	for i, net := range list.Plugins {
 		if err := c.checkNetwork(ctx, list.Name, list.CNIVersion, net, cachedResult, rt); err != nil {
-			return err
+			return withPluginIndex(err, i)
 		}
 	}
 
@@ -609,7 +699,7 @@ func (c *CNIConfig) delNetwork(ctx context.Context, name, cniVersion string, net
 	c.ensureExec()
 	pluginPath, err := c.exec.FindInPath(net.Network.Type, c.Path)
 	if err != nil {
-		return err
+		return newPluginError(net.Network, "DEL", err, 0)
 	}
 
 	newConf, err := buildOneConfig(name, cniVersion, net, prevResult, rt)
@@ -617,7 +707,11 @@ func (c *CNIConfig) delNetwork(ctx context.Context, name, cniVersion string, net
 		return err
 	}
 
-	return invoke.ExecPluginWithoutResult(ctx, pluginPath, newConf.Bytes, c.args("DEL", rt), c.exec)
+	start := time.Now()
+	if err := invoke.ExecPluginWithoutResult(ctx, pluginPath, newConf.Bytes, c.args("DEL", rt), c.exec); err != nil {
+		return newPluginError(net.Network, "DEL", err, time.Since(start))
+	}
+	return nil
 }
 
 // DelNetworkList executes a sequence of plugins with the DEL command
@@ -637,10 +731,10 @@ func (c *CNIConfig) DelNetworkList(ctx context.Context, list *NetworkConfigList,
 	for i := len(list.Plugins) - 1; i >= 0; i-- {
 		net := list.Plugins[i]
 		if err := c.delNetwork(ctx, list.Name, list.CNIVersion, net, cachedResult, rt); err != nil {
-			return fmt.Errorf("plugin %s failed (delete): %w", pluginDescription(net.Network), err)
+			return fmt.Errorf("plugin %s failed (delete): %w", pluginDescription(net.Network), withPluginIndex(err, i))
 		}
 	}
-	_ = c.cacheDel(list.Name, rt)
+	_ = c.RemoveCachedAttachment(list.Name, rt)
 
 	return nil
 }
@@ -705,7 +799,7 @@ func (c *CNIConfig) DelNetwork(ctx context.Context, net *NetworkConfig, rt *Runt
 	if err := c.delNetwork(ctx, net.Network.Name, net.Network.CNIVersion, net, cachedResult, rt); err != nil {
 		return err
 	}
-	_ = c.cacheDel(net.Network.Name, rt)
+	_ = c.RemoveCachedAttachment(net.Network.Name, rt)
 	return nil
 }
 
@@ -846,14 +940,14 @@ func (c *CNIConfig) GCNetworkList(ctx context.Context, list *NetworkConfigList,
 			"cniVersion":                list.CNIVersion,
 			"cni.dev/valid-attachments": args.ValidAttachments,
 		}
-		for _, plugin := range list.Plugins {
+		for i, plugin := range list.Plugins {
 			// build config here
 			pluginConfig, err := InjectConf(plugin, inject)
 			if err != nil {
 				errs = append(errs, fmt.Errorf("failed to generate configuration to GC plugin %s: %w", plugin.Network.Type, err))
 			}
 			if err := c.gcNetwork(ctx, pluginConfig); err != nil {
-				errs = append(errs, fmt.Errorf("failed to GC plugin %s: %w", plugin.Network.Type, err))
+				errs = append(errs, fmt.Errorf("failed to GC plugin %s: %w", plugin.Network.Type, withPluginIndex(err, i)))
 			}
 		}
 	}
@@ -865,11 +959,15 @@ func (c *CNIConfig) gcNetwork(ctx context.Context, net *NetworkConfig) error {
 	c.ensureExec()
 	pluginPath, err := c.exec.FindInPath(net.Network.Type, c.Path)
 	if err != nil {
-		return err
+		return newPluginError(net.Network, "GC", err, 0)
 	}
 	args := c.args("GC", &RuntimeConf{})
 
-	return invoke.ExecPluginWithoutResult(ctx, pluginPath, net.Bytes, args, c.exec)
+	start := time.Now()
+	if err := invoke.ExecPluginWithoutResult(ctx, pluginPath, net.Bytes, args, c.exec); err != nil {
+		return newPluginError(net.Network, "GC", err, time.Since(start))
+	}
+	return nil
 }
 
 func (c *CNIConfig) GetStatusNetworkList(ctx context.Context, list *NetworkConfigList) error {
@@ -883,14 +981,14 @@ func (c *CNIConfig) GetStatusNetworkList(ctx context.Context, list *NetworkConfi
 		"cniVersion": list.CNIVersion,
 	}
 
-	for _, plugin := range list.Plugins {
+	for i, plugin := range list.Plugins {
 		// build config here
 		pluginConfig, err := InjectConf(plugin, inject)
 		if err != nil {
 			return fmt.Errorf("failed to generate configuration to get plugin STATUS %s: %w", plugin.Network.Type, err)
 		}
 		if err := c.getStatusNetwork(ctx, pluginConfig); err != nil {
-			return err // Don't collect errors here, so we return a clean error code.
+			return withPluginIndex(err, i) // Don't collect errors here, so we return a clean error code.
 		}
 	}
 	return nil
@@ -900,11 +998,15 @@ func (c *CNIConfig) getStatusNetwork(ctx context.Context, net *NetworkConfig) er
 	c.ensureExec()
 	pluginPath, err := c.exec.FindInPath(net.Network.Type, c.Path)
 	if err != nil {
-		return err
+		return newPluginError(net.Network, "STATUS", err, 0)
 	}
 	args := c.args("STATUS", &RuntimeConf{})
 
-	return invoke.ExecPluginWithoutResult(ctx, pluginPath, net.Bytes, args, c.exec)
+	start := time.Now()
+	if err := invoke.ExecPluginWithoutResult(ctx, pluginPath, net.Bytes, args, c.exec); err != nil {
+		return newPluginError(net.Network, "STATUS", err, time.Since(start))
+	}
+	return nil
 }
 
 // =====
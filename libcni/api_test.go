@@ -20,6 +20,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"log/slog"
 	"net"
 	"os"
 	"path/filepath"
@@ -31,6 +32,7 @@ import (
 	. "github.com/onsi/gomega"
 
 	"github.com/containernetworking/cni/libcni"
+	"github.com/containernetworking/cni/pkg/invoke"
 	"github.com/containernetworking/cni/pkg/skel"
 	"github.com/containernetworking/cni/pkg/types"
 	current "github.com/containernetworking/cni/pkg/types/100"
@@ -464,6 +466,91 @@ var _ = Describe("Invoking plugins", func() {
 				})
 			})
 
+			Context("when result validation is enabled", func() {
+				BeforeEach(func() {
+					cniConfig = libcni.NewCNIConfigWithOptions([]string{filepath.Dir(pluginPaths["noop"])}, nil, libcni.WithResultValidation())
+					runtimeConfig.CacheDir = cacheDirPath
+				})
+
+				It("accepts a valid result", func() {
+					_, err := cniConfig.AddNetwork(ctx, netConfig, runtimeConfig)
+					Expect(err).NotTo(HaveOccurred())
+				})
+
+				It("rejects an invalid result", func() {
+					debug.ReportResult = `{
+						"cniVersion": "` + version.Current() + `",
+						"ips": [{"interface": 3, "address": "10.1.2.3/24", "gateway": "fd00::1"}]
+					}`
+					Expect(debug.WriteDebug(debugFilePath)).To(Succeed())
+
+					result, err := cniConfig.AddNetwork(ctx, netConfig, runtimeConfig)
+					Expect(result).To(BeNil())
+
+					var perr *libcni.PluginError
+					Expect(errors.As(err, &perr)).To(BeTrue())
+					Expect(perr.Err).To(Equal(&types.Error{
+						Code:    types.ErrDecodingFailure,
+						Msg:     "plugin returned an invalid result",
+						Details: "ips[0]: interface index 3 is out of range (result has 0 interfaces); ips[0]: IPv6 gateway fd00::1 for IPv4 address 10.1.2.3",
+					}))
+
+					cachedResult, err := cniConfig.GetNetworkCachedResult(netConfig, runtimeConfig)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(cachedResult).To(BeNil())
+				})
+			})
+
+			Context("when a logger is configured", func() {
+				var logBuffer *bytes.Buffer
+
+				BeforeEach(func() {
+					logBuffer = &bytes.Buffer{}
+					logger := slog.New(slog.NewJSONHandler(logBuffer, nil))
+					cniConfig = libcni.NewCNIConfigWithOptions([]string{filepath.Dir(pluginPaths["noop"])}, nil, libcni.WithLogger(logger))
+					runtimeConfig.CacheDir = cacheDirPath
+
+					debug.ReportStderr = "plugin says hello\n"
+					Expect(debug.WriteDebug(debugFilePath)).To(Succeed())
+				})
+
+				It("logs the plugin's stderr", func() {
+					_, err := cniConfig.AddNetwork(ctx, netConfig, runtimeConfig)
+					Expect(err).NotTo(HaveOccurred())
+
+					var record map[string]interface{}
+					Expect(json.Unmarshal(logBuffer.Bytes(), &record)).To(Succeed())
+					Expect(record).To(HaveKeyWithValue("msg", "plugin says hello"))
+					Expect(record).To(HaveKeyWithValue("plugin", "noop"))
+					Expect(record).To(HaveKeyWithValue("command", "ADD"))
+					Expect(record).To(HaveKeyWithValue("containerID", "some-container-id"))
+					Expect(record).To(HaveKeyWithValue("ifname", "some-eth0"))
+				})
+			})
+
+			Context("when a plugin verifier is configured", func() {
+				It("refuses to execute plugins it rejects", func() {
+					verifier := invoke.NewPluginVerifier(map[string]string{"noop": strings.Repeat("0", 64)})
+					verifier.SkipPermissionCheck = true
+					cniConfig = libcni.NewCNIConfigWithOptions([]string{filepath.Dir(pluginPaths["noop"])}, nil, libcni.WithPluginVerifier(verifier))
+					runtimeConfig.CacheDir = cacheDirPath
+
+					_, err := cniConfig.AddNetwork(ctx, netConfig, runtimeConfig)
+					Expect(err).To(MatchError(ContainSubstring("refusing to execute plugin")))
+				})
+			})
+
+			Context("when a cache directory is configured", func() {
+				It("caches the result there", func() {
+					cniConfig = libcni.NewCNIConfigWithOptions([]string{filepath.Dir(pluginPaths["noop"])}, nil, libcni.WithCacheDir(cacheDirPath))
+					runtimeConfig.CacheDir = ""
+
+					_, err := cniConfig.AddNetwork(ctx, netConfig, runtimeConfig)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(resultCacheFilePath(cacheDirPath, netConfig.Network.Name, runtimeConfig)).To(BeARegularFile())
+				})
+			})
+
 			Context("when the cache directory cannot be accessed", func() {
 				It("returns an error", func() {
 					// Make the results directory inaccessible by making it a
@@ -876,6 +963,34 @@ var _ = Describe("Invoking plugins", func() {
 			})
 		})
 
+		Describe("RemoveCachedAttachment", func() {
+			It("removes the cached result and config without executing the plugin", func() {
+				_, err := cniConfig.AddNetwork(ctx, netConfig, runtimeConfig)
+				Expect(err).NotTo(HaveOccurred())
+				debug.Command = ""
+				debug.CmdArgs = skel.CmdArgs{}
+				Expect(debug.WriteDebug(debugFilePath)).To(Succeed())
+
+				Expect(cniConfig.RemoveCachedAttachment(netConfig.Network.Name, runtimeConfig)).To(Succeed())
+
+				attachments, err := cniConfig.GetCachedAttachments("")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(attachments).To(BeEmpty())
+				cachedResult, err := cniConfig.GetNetworkCachedResult(netConfig, runtimeConfig)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(cachedResult).To(BeNil())
+
+				debug, err = noop_debug.ReadDebug(debugFilePath)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(debug.Command).To(BeEmpty())
+			})
+
+			It("returns an error if the attachment is not cached", func() {
+				err := cniConfig.RemoveCachedAttachment(netConfig.Network.Name, runtimeConfig)
+				Expect(err).To(MatchError(os.ErrNotExist))
+			})
+		})
+
 		Describe("GetVersionInfo", func() {
 			It("executes the plugin with the command VERSION", func() {
 				versionInfo, err := cniConfig.GetVersionInfo(ctx, "noop")
@@ -1066,6 +1181,13 @@ var _ = Describe("Invoking plugins", func() {
 				It("returns the error", func() {
 					_, err := cniConfig.AddNetworkList(ctx, netConfigList, runtimeConfig)
 					Expect(err).To(MatchError(ContainSubstring(`failed to find plugin "does-not-exist"`)))
+
+					var perr *libcni.PluginError
+					Expect(errors.As(err, &perr)).To(BeTrue())
+					Expect(perr.Index).To(Equal(1))
+					Expect(perr.Type).To(Equal("does-not-exist"))
+					Expect(perr.Err).To(BeNil())
+					Expect(perr.ExitStatus).To(Equal(-1))
 				})
 			})
 
@@ -1196,6 +1318,19 @@ var _ = Describe("Invoking plugins", func() {
 					Expect(errors.Unwrap(err)).To(MatchError("plugin error: banana"))
 					Expect(err.Error()).To(HavePrefix("plugin type=\"noop\" failed (add):"))
 				})
+				It("identifies the failing plugin", func() {
+					_, err := cniConfig.AddNetworkList(ctx, netConfigList, runtimeConfig)
+
+					var perr *libcni.PluginError
+					Expect(errors.As(err, &perr)).To(BeTrue())
+					Expect(perr.Index).To(Equal(1))
+					Expect(perr.Type).To(Equal("noop"))
+					Expect(perr.Command).To(Equal("ADD"))
+					Expect(perr.Code).To(Equal(types.ErrInternal))
+					Expect(perr.Err).To(Equal(&types.Error{Code: types.ErrInternal, Msg: "plugin error: banana"}))
+					Expect(perr.ExitStatus).To(Equal(1))
+					Expect(perr.Duration).To(BeNumerically(">", 0))
+				})
 				It("should not have written cache files", func() {
 					resultCacheFile := resultCacheFilePath(cacheDirPath, netConfigList.Name, runtimeConfig)
 					_, err := os.ReadFile(resultCacheFile)
@@ -1473,6 +1608,20 @@ var _ = Describe("Invoking plugins", func() {
 					Expect(errors.Unwrap(err)).To(MatchError("plugin error: banana"))
 					Expect(err.Error()).To(HavePrefix("plugin type=\"noop\" failed (delete):"))
 				})
+				It("identifies the failing plugin", func() {
+					plugins[1].debug.ReportErrorCode = types.ErrTryAgainLater
+					Expect(plugins[1].debug.WriteDebug(plugins[1].debugFilePath)).To(Succeed())
+
+					err := cniConfig.DelNetworkList(ctx, netConfigList, runtimeConfig)
+
+					var perr *libcni.PluginError
+					Expect(errors.As(err, &perr)).To(BeTrue())
+					Expect(perr.Index).To(Equal(1))
+					Expect(perr.Command).To(Equal("DEL"))
+					Expect(perr.Code).To(Equal(types.ErrTryAgainLater))
+					Expect(libcni.IsTransient(err)).To(BeTrue())
+					Expect(libcni.IsNotFound(err)).To(BeFalse())
+				})
 			})
 
 			Context("when the cached result is invalid", func() {
@@ -1488,6 +1637,99 @@ var _ = Describe("Invoking plugins", func() {
 				})
 			})
 		})
+		Describe("RenderNetworkList", func() {
+			It("renders ADD without executing the plugins", func() {
+				invocations, err := cniConfig.RenderNetworkList(ctx, netConfigList, "ADD", runtimeConfig)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(invocations).To(HaveLen(len(plugins)))
+
+				for i, inv := range invocations {
+					Expect(inv.Index).To(Equal(i))
+					Expect(inv.Type).To(Equal("noop"))
+					Expect(inv.Command).To(Equal("ADD"))
+					Expect(inv.Path).To(Equal(pluginPaths["noop"]))
+					Expect(inv.Env).To(ContainElements(
+						"CNI_COMMAND=ADD",
+						"CNI_CONTAINERID=some-container-id",
+						"CNI_NETNS=/some/netns/path",
+						"CNI_IFNAME=some-eth0",
+						"CNI_ARGS=FOO=BAR",
+						"CNI_PATH="+cniBinPath,
+					))
+
+					debug, err := noop_debug.ReadDebug(plugins[i].debugFilePath)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(debug.Command).To(BeEmpty())
+				}
+
+				Expect(invocations[0].PrevResult).To(Equal(libcni.PrevResultNone))
+				Expect(invocations[0].Stdin).To(MatchJSON(plugins[0].stdinData))
+
+				// Later plugins get a placeholder for the previous result
+				Expect(invocations[1].PrevResult).To(Equal(libcni.PrevResultPlaceholder))
+				stdin := map[string]interface{}{}
+				Expect(json.Unmarshal(invocations[1].Stdin, &stdin)).To(Succeed())
+				Expect(stdin["prevResult"]).To(HaveKeyWithValue("cniVersion", version.Current()))
+				Expect(stdin["prevResult"]).NotTo(HaveKey("ips"))
+				Expect(stdin["runtimeConfig"]).To(Equal(map[string]interface{}{"otherCapability": float64(33)}))
+			})
+
+			It("renders what DEL passes to the plugins", func() {
+				_, err := cniConfig.AddNetworkList(ctx, netConfigList, runtimeConfig)
+				Expect(err).NotTo(HaveOccurred())
+
+				invocations, err := cniConfig.RenderNetworkList(ctx, netConfigList, "DEL", runtimeConfig)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(invocations).To(HaveLen(len(plugins)))
+				Expect(invocations[0].Index).To(Equal(2))
+				Expect(invocations[2].Index).To(Equal(0))
+
+				Expect(cniConfig.DelNetworkList(ctx, netConfigList, runtimeConfig)).To(Succeed())
+				for _, inv := range invocations {
+					Expect(inv.PrevResult).To(Equal(libcni.PrevResultCached))
+					Expect(inv.Env).To(ContainElement("CNI_COMMAND=DEL"))
+
+					debug, err := noop_debug.ReadDebug(plugins[inv.Index].debugFilePath)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(debug.Command).To(Equal("DEL"))
+					Expect(debug.CmdArgs.StdinData).To(MatchJSON(inv.Stdin))
+				}
+			})
+
+			It("renders no plugins for CHECK when it is disabled", func() {
+				netConfigList.DisableCheck = true
+				invocations, err := cniConfig.RenderNetworkList(ctx, netConfigList, "CHECK", runtimeConfig)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(invocations).To(BeEmpty())
+			})
+
+			It("passes the deadline of the context", func() {
+				deadline := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+				ctx, cancel := context.WithDeadline(ctx, deadline)
+				defer cancel()
+				invocations, err := cniConfig.RenderNetworkList(ctx, netConfigList, "ADD", runtimeConfig)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(invocations[0].Env).To(ContainElement("CNI_DEADLINE=2030-01-02T03:04:05Z"))
+			})
+
+			It("returns an error for commands it cannot render", func() {
+				_, err := cniConfig.RenderNetworkList(ctx, netConfigList, "GC", runtimeConfig)
+				Expect(err).To(MatchError(`cannot render command "GC"`))
+			})
+
+			Context("when finding a plugin fails", func() {
+				It("returns a PluginError with its index", func() {
+					netConfigList.Plugins[1].Network.Type = "does-not-exist"
+					_, err := cniConfig.RenderNetworkList(ctx, netConfigList, "ADD", runtimeConfig)
+
+					var perr *libcni.PluginError
+					Expect(errors.As(err, &perr)).To(BeTrue())
+					Expect(perr.Index).To(Equal(1))
+					Expect(perr.Type).To(Equal("does-not-exist"))
+					Expect(perr.Command).To(Equal("ADD"))
+				})
+			})
+		})
 		Describe("ValidateNetworkList", func() {
 			It("Checks that all plugins exist", func() {
 				caps, err := cniConfig.ValidateNetworkList(ctx, netConfigList)
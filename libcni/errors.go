@@ -0,0 +1,135 @@
+// Copyright 2024 CNI authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package libcni
+
+import (
+	"errors"
+	"time"
+
+	"github.com/containernetworking/cni/pkg/invoke"
+	"github.com/containernetworking/cni/pkg/types"
+)
+
+// PluginError describes a plugin that could not be found or did not
+// complete a CNI command successfully. Errors returned by the CNIConfig
+// methods wrap a *PluginError whenever a plugin failed, so callers can
+// retrieve it with errors.As:
+//
+//	var perr *libcni.PluginError
+//	if errors.As(err, &perr) {
+//		log.Printf("plugin %d (%s) failed %s with code %d", perr.Index, perr.Type, perr.Command, perr.Code)
+//	}
+//
+// The message of a PluginError is that of the underlying error. For plugins
+// that failed with output on stderr, that message ends with the last part
+// of it, as "; stderr: ...", so match the plugin's error with errors.As
+// rather than by comparing error strings.
+type PluginError struct {
+	// Index is the position of the plugin in the network configuration
+	// list, or 0 for single network configurations
+	Index int
+	// Type and Name are the plugin's "type" and "name" config keys
+	Type string
+	Name string
+	// Command is the CNI command that failed, e.g. "ADD"
+	Command string
+
+	// Code is the CNI error code reported by the plugin, or
+	// types.ErrUnknown if the plugin did not report one
+	Code uint
+	// Err is the error reported by the plugin, if any
+	Err *types.Error
+
+	// ExitStatus is the plugin's exit code, or -1 if it is not known,
+	// e.g. because the plugin could not be found or started
+	ExitStatus int
+	// Stderr holds the last part of the plugin's stderr output, if known
+	Stderr []byte
+	// Duration is how long the plugin ran
+	Duration time.Duration
+
+	err error
+}
+
+func newPluginError(net *types.NetConf, command string, err error, duration time.Duration) *PluginError {
+	perr := &PluginError{
+		Command:    command,
+		ExitStatus: -1,
+		Duration:   duration,
+		err:        err,
+	}
+	if net != nil {
+		perr.Type = net.Type
+		perr.Name = net.Name
+	}
+
+	var execErr *invoke.ExecError
+	if errors.As(err, &execErr) {
+		perr.ExitStatus = execErr.ExitStatus
+		perr.Stderr = execErr.Stderr
+	}
+	if errors.As(err, &perr.Err) {
+		perr.Code = perr.Err.Code
+	}
+	return perr
+}
+
+// withPluginIndex records the position of the failing plugin in a
+// network configuration list
+func withPluginIndex(err error, index int) error {
+	var perr *PluginError
+	if errors.As(err, &perr) {
+		perr.Index = index
+	}
+	return err
+}
+
+func (e *PluginError) Error() string {
+	return e.err.Error()
+}
+
+func (e *PluginError) Unwrap() error {
+	return e.err
+}
+
+// errorCode returns the CNI error code carried by err, if any
+func errorCode(err error) (uint, bool) {
+	var terr *types.Error
+	if errors.As(err, &terr) {
+		return terr.Code, true
+	}
+	return 0, false
+}
+
+// IsTransient returns true if err reports a condition that the plugin
+// expects to clear, i.e. the operation should be retried later.
+func IsTransient(err error) bool {
+	code, ok := errorCode(err)
+	return ok && code == types.ErrTryAgainLater
+}
+
+// IsNotFound returns true if err reports that the container is unknown to
+// or does not exist for the plugin.
+func IsNotFound(err error) bool {
+	code, ok := errorCode(err)
+	return ok && code == types.ErrUnknownContainer
+}
+
+// IsIncompatibleVersion returns true if err reports that the plugin does
+// not support the requested CNI specification version.
+func IsIncompatibleVersion(err error) bool {
+	code, ok := errorCode(err)
+	return ok && code == types.ErrIncompatibleCNIVersion
+}
@@ -0,0 +1,49 @@
+// Copyright 2024 CNI authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package libcni_test
+
+import (
+	"errors"
+	"fmt"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/containernetworking/cni/libcni"
+	"github.com/containernetworking/cni/pkg/types"
+)
+
+var _ = Describe("Error helpers", func() {
+	DescribeTable("classify CNI error codes",
+		func(code uint, transient, notFound, incompatible bool) {
+			err := fmt.Errorf("plugin failed: %w", types.NewError(code, "some message", ""))
+			Expect(libcni.IsTransient(err)).To(Equal(transient))
+			Expect(libcni.IsNotFound(err)).To(Equal(notFound))
+			Expect(libcni.IsIncompatibleVersion(err)).To(Equal(incompatible))
+		},
+		Entry("try again later", types.ErrTryAgainLater, true, false, false),
+		Entry("unknown container", types.ErrUnknownContainer, false, true, false),
+		Entry("incompatible version", types.ErrIncompatibleCNIVersion, false, false, true),
+		Entry("internal error", types.ErrInternal, false, false, false),
+	)
+
+	It("returns false for errors without a CNI error code", func() {
+		err := errors.New("some error")
+		Expect(libcni.IsTransient(err)).To(BeFalse())
+		Expect(libcni.IsNotFound(err)).To(BeFalse())
+		Expect(libcni.IsIncompatibleVersion(err)).To(BeFalse())
+		Expect(libcni.IsTransient(nil)).To(BeFalse())
+	})
+})
@@ -0,0 +1,62 @@
+// Copyright 2024 CNI authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package fakecni provides a libcni.CNI for testing container runtimes
+// without executing plugins.
+package fakecni
+
+import (
+	"errors"
+
+	"github.com/containernetworking/cni/libcni"
+	"github.com/containernetworking/cni/pkg/invoke/fakes"
+)
+
+// PluginPath is the directory the simulated plugins are found in
+const PluginPath = "/opt/cni/bin"
+
+// CNI is a libcni.CNIConfig whose plugins are simulated in memory by a
+// fakes.PluginExec. It validates arguments, chains plugins and caches
+// results on disk as libcni does, and plugins of any type are simulated on
+// their first call. See fakes.PluginExec for how the plugins behave.
+type CNI struct {
+	*libcni.CNIConfig
+
+	// Exec simulates the plugins. Use it to customize plugins, inject
+	// faults and inspect the calls to the plugins.
+	Exec *fakes.PluginExec
+}
+
+var _ libcni.CNI = &CNI{}
+
+// New returns a CNI without attachments that caches results and
+// attachments in cacheDir, e.g. a test's temporary directory. cacheDir
+// must be set, as libcni would otherwise use the host's cache in
+// /var/lib/cni. The caller owns cacheDir and removes it when done.
+func New(cacheDir string) (*CNI, error) {
+	if cacheDir == "" {
+		return nil, errors.New("a cache directory is required")
+	}
+	exec := fakes.NewPluginExec()
+	exec.AutoAdd = true
+	return &CNI{
+		CNIConfig: libcni.NewCNIConfigWithCacheDir([]string{PluginPath}, cacheDir, exec),
+		Exec:      exec,
+	}, nil
+}
+
+// InjectFault makes all plugins handle matching calls as described by f
+func (c *CNI) InjectFault(f fakes.Fault) {
+	c.Exec.InjectFault(f)
+}
@@ -0,0 +1,244 @@
+// Copyright 2024 CNI authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fakecni_test
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/containernetworking/cni/libcni"
+	"github.com/containernetworking/cni/libcni/fakecni"
+	"github.com/containernetworking/cni/pkg/invoke/fakes"
+	"github.com/containernetworking/cni/pkg/types"
+	current "github.com/containernetworking/cni/pkg/types/100"
+)
+
+var _ = Describe("CNI", func() {
+	var (
+		fake *fakecni.CNI
+		list *libcni.NetworkConfigList
+		rt   *libcni.RuntimeConf
+		ctx  context.Context
+	)
+
+	BeforeEach(func() {
+		var err error
+		fake, err = fakecni.New(GinkgoT().TempDir())
+		Expect(err).NotTo(HaveOccurred())
+		list, err = libcni.ConfListFromBytes([]byte(`{
+			"cniVersion": "1.1.0",
+			"name": "some-net",
+			"plugins": [
+				{"type": "bridge", "capabilities": {"portMappings": true}},
+				{"type": "tuning", "capabilities": {"mac": true, "ips": false}}
+			]
+		}`))
+		Expect(err).NotTo(HaveOccurred())
+		rt = &libcni.RuntimeConf{
+			ContainerID:    "some-container",
+			NetNS:          "/some/netns",
+			IfName:         "eth0",
+			Args:           [][2]string{{"FOO", "BAR"}},
+			CapabilityArgs: map[string]interface{}{"mac": "00:11:22:33:44:55"},
+		}
+		ctx = context.TODO()
+	})
+
+	It("requires a cache directory", func() {
+		_, err := fakecni.New("")
+		Expect(err).To(MatchError("a cache directory is required"))
+	})
+
+	It("adds, checks and deletes attachments", func() {
+		r, err := fake.AddNetworkList(ctx, list, rt)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(r.Version()).To(Equal("1.1.0"))
+		result, err := current.GetResult(r)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.Interfaces).To(Equal([]*current.Interface{{Name: "eth0", Sandbox: "/some/netns"}}))
+		Expect(result.IPs[0].Address.String()).To(Equal("10.0.0.2/24"))
+
+		Expect(fake.CheckNetworkList(ctx, list, rt)).To(Succeed())
+
+		cached, err := fake.GetNetworkListCachedResult(list, rt)
+		Expect(err).NotTo(HaveOccurred())
+		cachedResult, err := current.GetResult(cached)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cachedResult.IPs[0].Address.String()).To(Equal("10.0.0.2/24"))
+
+		config, cachedRt, err := fake.GetNetworkListCachedConfig(list, &libcni.RuntimeConf{ContainerID: "some-container", IfName: "eth0"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(config).To(Equal(list.Bytes))
+		Expect(cachedRt.Args).To(Equal(rt.Args))
+		Expect(cachedRt.CapabilityArgs).To(Equal(rt.CapabilityArgs))
+
+		attachments, err := fake.GetCachedAttachments("some-container")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(attachments).To(HaveLen(1))
+		Expect(attachments[0].Network).To(Equal("some-net"))
+		Expect(attachments[0].IfName).To(Equal("eth0"))
+		Expect(attachments[0].NetNS).To(Equal("/some/netns"))
+
+		Expect(fake.DelNetworkList(ctx, list, rt)).To(Succeed())
+		cached, err = fake.GetNetworkListCachedResult(list, rt)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cached).To(BeNil())
+		Expect(fake.Exec.Plugin("bridge").Attachments()).To(BeEmpty())
+
+		commands := []string{}
+		for _, c := range fake.Exec.Calls() {
+			Expect(c.Network).To(Equal("some-net"))
+			commands = append(commands, c.Command+" "+c.Plugin)
+		}
+		Expect(commands).To(Equal([]string{
+			"ADD bridge", "ADD tuning",
+			"CHECK bridge", "CHECK tuning",
+			"DEL tuning", "DEL bridge",
+		}))
+	})
+
+	It("handles single network configs", func() {
+		net, err := libcni.ConfFromBytes([]byte(`{"cniVersion": "1.0.0", "name": "some-net", "type": "bridge"}`))
+		Expect(err).NotTo(HaveOccurred())
+		_, err = fake.AddNetwork(ctx, net, rt)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(fake.CheckNetwork(ctx, net, rt)).To(Succeed())
+		cached, err := fake.GetNetworkCachedResult(net, rt)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cached).NotTo(BeNil())
+		config, _, err := fake.GetNetworkCachedConfig(net, rt)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(config).To(Equal(net.Bytes))
+		Expect(fake.DelNetwork(ctx, net, rt)).To(Succeed())
+		Expect(fake.CheckNetwork(ctx, net, rt)).NotTo(Succeed())
+	})
+
+	It("validates the runtime config like libcni", func() {
+		rt.IfName = ""
+		_, err := fake.AddNetworkList(ctx, list, rt)
+		var cniErr *types.Error
+		Expect(errors.As(err, &cniErr)).To(BeTrue())
+		Expect(cniErr.Code).To(Equal(uint(types.ErrInvalidEnvironmentVariables)))
+		Expect(fake.Exec.Calls()).To(BeEmpty())
+	})
+
+	It("fails CHECK for attachments that were not added", func() {
+		Expect(fake.CheckNetworkList(ctx, list, rt)).NotTo(Succeed())
+	})
+
+	It("does not support CHECK before version 0.4.0", func() {
+		list.CNIVersion = "0.3.1"
+		Expect(fake.CheckNetworkList(ctx, list, rt)).To(MatchError(ContainSubstring(libcni.ErrorCheckNotSupp.Error())))
+	})
+
+	It("succeeds to delete unknown attachments", func() {
+		Expect(fake.DelNetworkList(ctx, list, rt)).To(Succeed())
+	})
+
+	It("removes the attachments to the network that are not valid on GC", func() {
+		other := *rt
+		other.ContainerID = "other-container"
+		otherList := *list
+		otherList.Name = "other-net"
+		for _, c := range []struct {
+			list *libcni.NetworkConfigList
+			rt   *libcni.RuntimeConf
+		}{{list, rt}, {list, &other}, {&otherList, rt}} {
+			_, err := fake.AddNetworkList(ctx, c.list, c.rt)
+			Expect(err).NotTo(HaveOccurred())
+		}
+
+		Expect(fake.GCNetworkList(ctx, list, &libcni.GCArgs{
+			ValidAttachments: []libcni.GCAttachment{{ContainerID: "other-container", IfName: "eth0"}},
+		})).To(Succeed())
+
+		attachments, err := fake.GetCachedAttachments("")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(attachments).To(HaveLen(2))
+		networks := map[string]string{}
+		for _, a := range attachments {
+			networks[a.Network] = a.ContainerID
+		}
+		Expect(networks).To(Equal(map[string]string{"some-net": "other-container", "other-net": "some-container"}))
+	})
+
+	It("returns the enabled capabilities on validation", func() {
+		caps, err := fake.ValidateNetworkList(ctx, list)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(caps).To(ConsistOf("mac", "portMappings"))
+	})
+
+	It("uses the plugins' Result funcs", func() {
+		fake.Exec.AddPlugin("tuning").Result = func(call *fakes.PluginCall, prevResult *current.Result) (*current.Result, error) {
+			prevResult.DNS.Domain = call.Network
+			return prevResult, nil
+		}
+		r, err := fake.AddNetworkList(ctx, list, rt)
+		Expect(err).NotTo(HaveOccurred())
+		result, err := current.GetResult(r)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.DNS.Domain).To(Equal("some-net"))
+	})
+
+	Context("with faults", func() {
+		It("fails the Nth matching call without caching a result", func() {
+			fake.InjectFault(fakes.Fault{
+				Command: "ADD",
+				Call:    3,
+				Err:     types.NewError(types.ErrTryAgainLater, "busy", ""),
+			})
+			_, err := fake.AddNetworkList(ctx, list, rt)
+			Expect(err).NotTo(HaveOccurred())
+
+			other := *rt
+			other.ContainerID = "other-container"
+			_, err = fake.AddNetworkList(ctx, list, &other)
+			var cniErr *types.Error
+			Expect(errors.As(err, &cniErr)).To(BeTrue())
+			Expect(cniErr.Code).To(Equal(uint(types.ErrTryAgainLater)))
+			cached, err := fake.GetNetworkListCachedResult(list, &other)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cached).To(BeNil())
+
+			_, err = fake.AddNetworkList(ctx, list, &other)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("only apply to their network", func() {
+			fake.InjectFault(fakes.Fault{Network: "other-net", Err: types.NewError(types.ErrInternal, "boom", "")})
+			Expect(fake.GetStatusNetworkList(ctx, list)).To(Succeed())
+			list.Name = "other-net"
+			Expect(fake.GetStatusNetworkList(ctx, list)).To(MatchError(ContainSubstring("boom")))
+		})
+
+		It("delay calls", func() {
+			fake.InjectFault(fakes.Fault{Command: "STATUS", Delay: 50 * time.Millisecond})
+			start := time.Now()
+			Expect(fake.GetStatusNetworkList(ctx, list)).To(Succeed())
+			Expect(time.Since(start)).To(BeNumerically(">=", 50*time.Millisecond))
+		})
+
+		It("hang until the context is done", func() {
+			fake.InjectFault(fakes.Fault{Command: "DEL", Hang: true})
+			ctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+			defer cancel()
+			Expect(fake.DelNetworkList(ctx, list, rt)).To(MatchError(ContainSubstring("context deadline exceeded")))
+		})
+	})
+})
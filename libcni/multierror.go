@@ -56,3 +56,7 @@ func (e *multiError) Error() string {
 	}
 	return string(b)
 }
+
+func (e *multiError) Unwrap() []error {
+	return e.errs
+}
@@ -0,0 +1,164 @@
+// Copyright 2024 CNI authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package libcni
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/containernetworking/cni/pkg/types"
+	"github.com/containernetworking/cni/pkg/types/create"
+	"github.com/containernetworking/cni/pkg/utils"
+	"github.com/containernetworking/cni/pkg/version"
+)
+
+// PrevResultSource tells where the prevResult passed to a plugin comes from
+type PrevResultSource string
+
+const (
+	// PrevResultNone means the plugin gets no prevResult
+	PrevResultNone PrevResultSource = "none"
+	// PrevResultCached means the prevResult is the cached result of the
+	// last ADD, as passed on CHECK and DEL
+	PrevResultCached PrevResultSource = "cached"
+	// PrevResultPlaceholder means the prevResult is an empty result
+	// standing in for the result of the previous plugin on ADD, which is
+	// only known once that plugin ran
+	PrevResultPlaceholder PrevResultSource = "placeholder"
+)
+
+// PluginInvocation describes how a plugin of a network configuration list
+// is executed for a CNI command
+type PluginInvocation struct {
+	// Index is the position of the plugin in the network configuration list
+	Index int
+	// Type and Name are the plugin's "type" and "name" config keys
+	Type string
+	Name string
+	// Command is the CNI command, e.g. "ADD"
+	Command string
+	// Path is the resolved path of the plugin binary
+	Path string
+	// Env is the plugin's environment, as built by invoke.Args.AsEnv. It
+	// includes the environment of the calling process.
+	Env []string
+	// Stdin is the network configuration the plugin reads from stdin
+	Stdin []byte
+	// PrevResult tells where the prevResult in Stdin comes from
+	PrevResult PrevResultSource
+}
+
+// RenderNetworkList returns how each plugin of list would be executed for
+// command, one of "ADD", "CHECK" or "DEL", without executing any plugin.
+// Plugins are returned in the order they would run, so in reverse for DEL.
+// The deadline of ctx, if any, is passed in CNI_DEADLINE.
+//
+// The result of the previous plugin in an ADD chain cannot be known without
+// running it, so it is replaced with an empty result of the list's version.
+// CHECK and DEL get the cached result of the last ADD, like they would when
+// executed. If CHECK is disabled for list, no plugins are returned.
+func (c *CNIConfig) RenderNetworkList(ctx context.Context, list *NetworkConfigList, command string, rt *RuntimeConf) ([]*PluginInvocation, error) {
+	var cachedResult types.Result
+	switch command {
+	case "ADD":
+		if err := utils.ValidateContainerID(rt.ContainerID); err != nil {
+			return nil, err
+		}
+		if err := utils.ValidateNetworkName(list.Name); err != nil {
+			return nil, err
+		}
+		if err := utils.ValidateInterfaceName(rt.IfName); err != nil {
+			return nil, err
+		}
+	case "CHECK":
+		if gtet, err := version.GreaterThanOrEqualTo(list.CNIVersion, "0.4.0"); err != nil {
+			return nil, err
+		} else if !gtet {
+			return nil, fmt.Errorf("configuration version %q %w", list.CNIVersion, ErrorCheckNotSupp)
+		}
+		if list.DisableCheck {
+			return []*PluginInvocation{}, nil
+		}
+		var err error
+		if cachedResult, err = c.getCachedResult(list.Name, list.CNIVersion, rt); err != nil {
+			return nil, fmt.Errorf("failed to get network %q cached result: %w", list.Name, err)
+		}
+	case "DEL":
+		if gtet, err := version.GreaterThanOrEqualTo(list.CNIVersion, "0.4.0"); err != nil {
+			return nil, err
+		} else if gtet {
+			if cachedResult, err = c.getCachedResult(list.Name, list.CNIVersion, rt); err != nil {
+				return nil, fmt.Errorf("failed to get network %q cached result: %w", list.Name, err)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("cannot render command %q", command)
+	}
+
+	args := c.args(command, rt)
+	if deadline, ok := ctx.Deadline(); ok {
+		args.Deadline = deadline
+	}
+	env := args.AsEnv()
+
+	c.ensureExec()
+	invocations := make([]*PluginInvocation, 0, len(list.Plugins))
+	for i, net := range list.Plugins {
+		pluginPath, err := c.exec.FindInPath(net.Network.Type, c.Path)
+		if err != nil {
+			return nil, withPluginIndex(newPluginError(net.Network, command, err, 0), i)
+		}
+
+		prevResult, source := cachedResult, PrevResultCached
+		if command == "ADD" && i > 0 {
+			prevResult, source = placeholderResult(list.CNIVersion)
+		}
+		if prevResult == nil {
+			source = PrevResultNone
+		}
+
+		newConf, err := buildOneConfig(list.Name, list.CNIVersion, net, prevResult, rt)
+		if err != nil {
+			return nil, err
+		}
+		invocations = append(invocations, &PluginInvocation{
+			Index:      i,
+			Type:       net.Network.Type,
+			Name:       net.Network.Name,
+			Command:    command,
+			Path:       pluginPath,
+			Env:        append([]string(nil), env...),
+			Stdin:      newConf.Bytes,
+			PrevResult: source,
+		})
+	}
+
+	if command == "DEL" {
+		for i, j := 0, len(invocations)-1; i < j; i, j = i+1, j-1 {
+			invocations[i], invocations[j] = invocations[j], invocations[i]
+		}
+	}
+	return invocations, nil
+}
+
+// placeholderResult returns an empty result of the given version, or no
+// result if the version has none
+func placeholderResult(cniVersion string) (types.Result, PrevResultSource) {
+	result, err := create.Create(cniVersion, []byte(fmt.Sprintf(`{"cniVersion":%q}`, cniVersion)))
+	if err != nil {
+		return nil, PrevResultNone
+	}
+	return result, PrevResultPlaceholder
+}
@@ -0,0 +1,355 @@
+// Copyright 2024 CNI authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package conformance checks that a plugin binary behaves as the CNI
+// specification requires: that it reports its versions, returns results of
+// the configured version, handles ADD, CHECK and DEL in sequence, tolerates
+// repeated DELs and missing network namespaces, reports errors as JSON and
+// supports GC and STATUS from version 1.1.0 on.
+package conformance
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/containernetworking/cni/pkg/invoke"
+	"github.com/containernetworking/cni/pkg/types"
+	"github.com/containernetworking/cni/pkg/types/create"
+	"github.com/containernetworking/cni/pkg/version"
+)
+
+// The checks run for each CNI version, in order
+const (
+	CheckVersion         = "version"
+	CheckAdd             = "add"
+	CheckResultVersion   = "result-version"
+	CheckCheck           = "check"
+	CheckDel             = "del"
+	CheckDelIdempotent   = "del-idempotent"
+	CheckDelMissingNetns = "del-missing-netns"
+	CheckErrorJSON       = "error-json"
+	CheckGC              = "gc"
+	CheckStatus          = "status"
+)
+
+// Checks lists the checks in the order they are run and reported
+var Checks = []string{
+	CheckVersion,
+	CheckAdd,
+	CheckResultVersion,
+	CheckCheck,
+	CheckDel,
+	CheckDelIdempotent,
+	CheckDelMissingNetns,
+	CheckErrorJSON,
+	CheckGC,
+	CheckStatus,
+}
+
+// Suite runs the conformance checks against a plugin binary
+type Suite struct {
+	// PluginPath is the path of the plugin binary. Its directory is passed
+	// to the plugin in CNI_PATH.
+	PluginPath string
+
+	// Config holds plugin-specific configuration keys, e.g. "ipam". The
+	// "cniVersion" key is set for each version tested; "name" and "type"
+	// default to "cni-conformance" and the plugin's file name.
+	Config []byte
+
+	// Versions are the CNI versions to test. They default to the versions
+	// the plugin reports supporting.
+	Versions []string
+
+	// Netns is the network namespace containers are attached to. If empty,
+	// a namespace is created for each version with NewNetNS.
+	Netns string
+
+	// NewNetNS creates a network namespace and returns its path and a
+	// function removing it. It defaults to creating a named namespace in
+	// /var/run/netns, which requires root on Linux. The del-missing-netns
+	// check uses it even if Netns is set, as it removes the namespace, and
+	// is skipped if it fails.
+	NewNetNS func() (string, func() error, error)
+
+	// IfName is the interface name passed to the plugin. It defaults to
+	// "eth0".
+	IfName string
+
+	// Exec executes the plugin; it defaults to executing the binary
+	Exec invoke.Exec
+}
+
+// Run runs all checks for every version and returns the report. It only
+// returns an error if the suite itself is misconfigured; plugin failures
+// are recorded in the report.
+func (s *Suite) Run(ctx context.Context) (*Report, error) {
+	if s.PluginPath == "" {
+		return nil, fmt.Errorf("no plugin given")
+	}
+	base := map[string]interface{}{}
+	if len(s.Config) > 0 {
+		if err := json.Unmarshal(s.Config, &base); err != nil {
+			return nil, fmt.Errorf("failed to decode plugin configuration: %w", err)
+		}
+	}
+	if _, ok := base["name"]; !ok {
+		base["name"] = "cni-conformance"
+	}
+	if _, ok := base["type"]; !ok {
+		base["type"] = filepath.Base(s.PluginPath)
+	}
+
+	report := &Report{Plugin: s.PluginPath}
+
+	info, versionErr := invoke.GetVersionInfo(ctx, s.PluginPath, s.exec())
+	if versionErr == nil {
+		report.SupportedVersions = info.SupportedVersions()
+	}
+
+	versions := s.Versions
+	if len(versions) == 0 {
+		versions = report.SupportedVersions
+	}
+	if len(versions) == 0 {
+		report.add(CheckVersion, "", Fail, fmt.Sprintf("VERSION failed: %v", versionErr))
+		return report, nil
+	}
+	report.Versions = versions
+
+	for _, v := range versions {
+		if versionErr != nil {
+			report.add(CheckVersion, v, Fail, fmt.Sprintf("VERSION failed: %v", versionErr))
+			report.skipRest(v, "VERSION failed")
+			continue
+		}
+		if !contains(report.SupportedVersions, v) {
+			report.add(CheckVersion, v, Fail, fmt.Sprintf("version %s is not reported as supported", v))
+			report.skipRest(v, "version not supported")
+			continue
+		}
+		report.add(CheckVersion, v, Pass, "")
+		if err := s.runVersion(ctx, report, base, v); err != nil {
+			return nil, err
+		}
+	}
+	return report, nil
+}
+
+func contains(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Suite) exec() invoke.Exec {
+	if s.Exec == nil {
+		return &invoke.DefaultExec{RawExec: &invoke.RawExec{}}
+	}
+	return s.Exec
+}
+
+func (s *Suite) ifName() string {
+	if s.IfName == "" {
+		return "eth0"
+	}
+	return s.IfName
+}
+
+// run executes the plugin and returns its raw output
+func (s *Suite) run(ctx context.Context, conf map[string]interface{}, args *invoke.Args) ([]byte, error) {
+	stdin, err := json.Marshal(conf)
+	if err != nil {
+		return nil, err
+	}
+	return s.runRaw(ctx, stdin, args)
+}
+
+func (s *Suite) runRaw(ctx context.Context, stdin []byte, args *invoke.Args) ([]byte, error) {
+	args.Path = filepath.Dir(s.PluginPath)
+	return s.exec().ExecPlugin(ctx, s.PluginPath, stdin, args.AsEnv())
+}
+
+// configFor returns a copy of base for version v, with the given extra keys
+func configFor(base map[string]interface{}, v string, extra map[string]interface{}) map[string]interface{} {
+	conf := make(map[string]interface{}, len(base)+len(extra)+1)
+	for k, val := range base {
+		conf[k] = val
+	}
+	conf["cniVersion"] = v
+	for k, val := range extra {
+		conf[k] = val
+	}
+	return conf
+}
+
+func atLeast(v, min string) bool {
+	ok, err := version.GreaterThanOrEqualTo(v, min)
+	return err == nil && ok
+}
+
+func (s *Suite) newNetNS() (string, func() error, error) {
+	if s.NewNetNS == nil {
+		return defaultNewNetNS()
+	}
+	return s.NewNetNS()
+}
+
+func (s *Suite) runVersion(ctx context.Context, report *Report, base map[string]interface{}, v string) error {
+	netns := s.Netns
+	if netns == "" {
+		path, cleanup, err := s.newNetNS()
+		if err != nil {
+			return fmt.Errorf("failed to create network namespace: %w", err)
+		}
+		defer func() { _ = cleanup() }()
+		netns = path
+	}
+
+	containerID := "cni-conformance-" + v
+	args := func(command string) *invoke.Args {
+		return &invoke.Args{Command: command, ContainerID: containerID, NetNS: netns, IfName: s.ifName()}
+	}
+
+	// ADD, then CHECK and DEL with the result as prevResult
+	conf := configFor(base, v, nil)
+	var prevResult map[string]interface{}
+	stdout, err := s.run(ctx, conf, args("ADD"))
+	if err != nil {
+		report.add(CheckAdd, v, Fail, fmt.Sprintf("ADD failed: %v", err))
+		report.add(CheckResultVersion, v, Skip, "ADD failed")
+	} else {
+		report.add(CheckAdd, v, Pass, "")
+		prevResult, err = checkResult(stdout, v)
+		if err != nil {
+			report.add(CheckResultVersion, v, Fail, err.Error())
+		} else {
+			report.add(CheckResultVersion, v, Pass, "")
+		}
+	}
+
+	var withPrev map[string]interface{}
+	if prevResult != nil && atLeast(v, "0.4.0") {
+		withPrev = configFor(base, v, map[string]interface{}{"prevResult": prevResult})
+	}
+
+	switch {
+	case !atLeast(v, "0.4.0"):
+		report.add(CheckCheck, v, Skip, "CHECK requires version 0.4.0")
+	case withPrev == nil:
+		report.add(CheckCheck, v, Skip, "no result from ADD")
+	default:
+		report.addErr(CheckCheck, v, "CHECK", s.runErr(ctx, withPrev, args("CHECK")))
+	}
+
+	delConf := conf
+	if withPrev != nil {
+		delConf = withPrev
+	}
+	report.addErr(CheckDel, v, "DEL", s.runErr(ctx, delConf, args("DEL")))
+	report.addErr(CheckDelIdempotent, v, "second DEL", s.runErr(ctx, delConf, args("DEL")))
+
+	s.checkDelMissingNetns(ctx, report, base, v, containerID+"-missing-netns")
+
+	// Invalid configuration must be reported as an error on stdout
+	_, err = s.runRaw(ctx, []byte(fmt.Sprintf(`{"cniVersion":%q,"name":`, v)), args("ADD"))
+	if err := checkErrorJSON(err); err != nil {
+		report.add(CheckErrorJSON, v, Fail, err.Error())
+	} else {
+		report.add(CheckErrorJSON, v, Pass, "")
+	}
+
+	if !atLeast(v, "1.1.0") {
+		report.add(CheckGC, v, Skip, "GC requires version 1.1.0")
+		report.add(CheckStatus, v, Skip, "STATUS requires version 1.1.0")
+		return nil
+	}
+	gcConf := configFor(base, v, map[string]interface{}{"cni.dev/valid-attachments": []interface{}{}})
+	report.addErr(CheckGC, v, "GC", s.runErr(ctx, gcConf, &invoke.Args{Command: "GC"}))
+	report.addErr(CheckStatus, v, "STATUS", s.runErr(ctx, conf, &invoke.Args{Command: "STATUS"}))
+	return nil
+}
+
+// checkDelMissingNetns adds a container, removes its namespace and checks
+// that the container can still be deleted
+func (s *Suite) checkDelMissingNetns(ctx context.Context, report *Report, base map[string]interface{}, v, containerID string) {
+	netns, cleanup, err := s.newNetNS()
+	if err != nil {
+		report.add(CheckDelMissingNetns, v, Skip, fmt.Sprintf("failed to create network namespace: %v", err))
+		return
+	}
+	args := &invoke.Args{Command: "ADD", ContainerID: containerID, NetNS: netns, IfName: s.ifName()}
+	conf := configFor(base, v, nil)
+	stdout, err := s.run(ctx, conf, args)
+	if err != nil {
+		_ = cleanup()
+		report.add(CheckDelMissingNetns, v, Skip, "ADD failed")
+		return
+	}
+	if prevResult, err := checkResult(stdout, v); err == nil && atLeast(v, "0.4.0") {
+		conf = configFor(base, v, map[string]interface{}{"prevResult": prevResult})
+	}
+	removeErr := cleanup()
+
+	args.Command = "DEL"
+	err = s.runErr(ctx, conf, args)
+	if removeErr != nil {
+		report.add(CheckDelMissingNetns, v, Skip, fmt.Sprintf("failed to remove network namespace: %v", removeErr))
+		return
+	}
+	report.addErr(CheckDelMissingNetns, v, "DEL", err)
+}
+
+func (s *Suite) runErr(ctx context.Context, conf map[string]interface{}, args *invoke.Args) error {
+	_, err := s.run(ctx, conf, args)
+	return err
+}
+
+// checkResult checks that stdout is a result of version v and returns it
+// decoded for use as prevResult
+func checkResult(stdout []byte, v string) (map[string]interface{}, error) {
+	var raw map[string]interface{}
+	if err := json.Unmarshal(stdout, &raw); err != nil {
+		return nil, fmt.Errorf("result is not valid JSON: %v", err)
+	}
+	resultVersion, _ := raw["cniVersion"].(string)
+	if resultVersion != v {
+		return nil, fmt.Errorf("result has cniVersion %q, expected %q", resultVersion, v)
+	}
+	if _, err := create.Create(v, stdout); err != nil {
+		return nil, fmt.Errorf("result cannot be decoded: %v", err)
+	}
+	return raw, nil
+}
+
+// checkErrorJSON checks that err is an error the plugin printed as JSON
+func checkErrorJSON(err error) error {
+	if err == nil {
+		return fmt.Errorf("plugin succeeded on an invalid configuration")
+	}
+	var e *types.Error
+	if !errors.As(err, &e) || e.Code == 0 {
+		return fmt.Errorf("plugin did not print an error as JSON: %v", err)
+	}
+	if e.Msg == "" {
+		return fmt.Errorf("plugin error %d has no msg", e.Code)
+	}
+	return nil
+}
@@ -0,0 +1,278 @@
+// Copyright 2024 CNI authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package conformance_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/containernetworking/cni/pkg/conformance"
+	"github.com/containernetworking/cni/pkg/skel"
+	"github.com/containernetworking/cni/pkg/skel/skeltest"
+	"github.com/containernetworking/cni/pkg/types"
+	current "github.com/containernetworking/cni/pkg/types/100"
+	"github.com/containernetworking/cni/pkg/version"
+)
+
+// skelExec executes a skeltest.Plugin in-process
+type skelExec struct {
+	version.PluginDecoder
+	plugin *skeltest.Plugin
+	// rawErrors makes errors be returned without their JSON form
+	rawErrors bool
+}
+
+func (e *skelExec) ExecPlugin(ctx context.Context, _ string, stdinData []byte, environ []string) ([]byte, error) {
+	env := map[string]string{}
+	for _, kv := range environ {
+		if k, v, ok := strings.Cut(kv, "="); ok {
+			env[k] = v
+		}
+	}
+	out := e.plugin.Run(ctx, env["CNI_COMMAND"], skeltest.Args{Env: env}, stdinData)
+	if out.Err != nil {
+		if e.rawErrors {
+			return nil, errors.New(out.Err.Msg)
+		}
+		return nil, out.Err
+	}
+	return out.Stdout, nil
+}
+
+func (e *skelExec) FindInPath(plugin string, _ []string) (string, error) {
+	return "/fake/" + plugin, nil
+}
+
+// fakePlugin is a well-behaved plugin that tracks attached containers
+type fakePlugin struct {
+	attached map[string]bool
+	// resultVersion, if set, is used for results instead of the config's
+	resultVersion string
+	// strictDel makes DEL fail for containers that are not attached
+	strictDel bool
+	// removedNetns holds the namespaces that were removed, and
+	// netnsDel makes DEL fail for them
+	removedNetns map[string]bool
+	netnsDel     bool
+}
+
+func (p *fakePlugin) funcs() skel.CNIFuncs {
+	return skel.CNIFuncs{
+		Add: func(args *skel.CmdArgs) error {
+			conf := &types.NetConf{}
+			if err := json.Unmarshal(args.StdinData, conf); err != nil {
+				return err
+			}
+			p.attached[args.ContainerID] = true
+			ipnet, _ := types.ParseCIDR("10.0.0.2/24")
+			resultVersion := conf.CNIVersion
+			if p.resultVersion != "" {
+				resultVersion = p.resultVersion
+			}
+			return args.PrintResult(&current.Result{
+				CNIVersion: current.ImplementedSpecVersion,
+				IPs:        []*current.IPConfig{{Address: *ipnet}},
+			}, resultVersion)
+		},
+		Check: func(args *skel.CmdArgs) error {
+			if !p.attached[args.ContainerID] {
+				return fmt.Errorf("container %s is not attached", args.ContainerID)
+			}
+			if !bytes.Contains(args.StdinData, []byte("prevResult")) {
+				return fmt.Errorf("missing prevResult")
+			}
+			return nil
+		},
+		Del: func(args *skel.CmdArgs) error {
+			if p.strictDel && !p.attached[args.ContainerID] {
+				return fmt.Errorf("container %s is not attached", args.ContainerID)
+			}
+			if p.netnsDel && p.removedNetns[args.Netns] {
+				return fmt.Errorf("failed to open netns %q", args.Netns)
+			}
+			delete(p.attached, args.ContainerID)
+			return nil
+		},
+		GC:     func(*skel.CmdArgs) error { return nil },
+		Status: func(*skel.CmdArgs) error { return nil },
+	}
+}
+
+var _ = Describe("the conformance suite", func() {
+	var (
+		plugin *fakePlugin
+		exec   *skelExec
+		suite  *conformance.Suite
+	)
+
+	BeforeEach(func() {
+		plugin = &fakePlugin{attached: map[string]bool{}, removedNetns: map[string]bool{}}
+		exec = &skelExec{plugin: skeltest.New(plugin.funcs(), version.PluginSupports("0.3.1", "1.0.0", "1.1.0"))}
+		created := 0
+		suite = &conformance.Suite{
+			PluginPath: "/fake/plugin",
+			Netns:      "/var/run/netns/fake",
+			NewNetNS: func() (string, func() error, error) {
+				created++
+				path := fmt.Sprintf("/var/run/netns/created%d", created)
+				return path, func() error { plugin.removedNetns[path] = true; return nil }, nil
+			},
+			Exec: exec,
+		}
+	})
+
+	statusOf := func(report *conformance.Report, check, v string) conformance.Status {
+		res, ok := report.Get(check, v)
+		ExpectWithOffset(1, ok).To(BeTrue(), "no result for %s %s", check, v)
+		return res.Status
+	}
+
+	It("passes a well-behaved plugin for all supported versions", func() {
+		report, err := suite.Run(context.TODO())
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(report.SupportedVersions).To(Equal([]string{"0.3.1", "1.0.0", "1.1.0"}))
+		Expect(report.Versions).To(Equal(report.SupportedVersions))
+		Expect(report.Passed()).To(BeTrue(), "%+v", report.Results)
+		Expect(report.Results).To(HaveLen(len(conformance.Checks) * 3))
+
+		Expect(statusOf(report, conformance.CheckCheck, "0.3.1")).To(Equal(conformance.Skip))
+		Expect(statusOf(report, conformance.CheckGC, "1.0.0")).To(Equal(conformance.Skip))
+		Expect(statusOf(report, conformance.CheckGC, "1.1.0")).To(Equal(conformance.Pass))
+		Expect(statusOf(report, conformance.CheckStatus, "1.1.0")).To(Equal(conformance.Pass))
+		Expect(plugin.attached).To(BeEmpty())
+	})
+
+	It("fails versions the plugin does not support", func() {
+		suite.Versions = []string{"1.0.0", "0.4.0"}
+		report, err := suite.Run(context.TODO())
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(report.Passed()).To(BeFalse())
+		Expect(statusOf(report, conformance.CheckVersion, "1.0.0")).To(Equal(conformance.Pass))
+		Expect(statusOf(report, conformance.CheckVersion, "0.4.0")).To(Equal(conformance.Fail))
+		Expect(statusOf(report, conformance.CheckAdd, "0.4.0")).To(Equal(conformance.Skip))
+	})
+
+	It("fails a result of the wrong version", func() {
+		plugin.resultVersion = "1.0.0"
+		report, err := suite.Run(context.TODO())
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(statusOf(report, conformance.CheckResultVersion, "1.0.0")).To(Equal(conformance.Pass))
+		res, _ := report.Get(conformance.CheckResultVersion, "1.1.0")
+		Expect(res.Status).To(Equal(conformance.Fail))
+		Expect(res.Message).To(Equal(`result has cniVersion "1.0.0", expected "1.1.0"`))
+	})
+
+	It("fails a plugin whose DEL is not idempotent", func() {
+		plugin.strictDel = true
+		report, err := suite.Run(context.TODO())
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(statusOf(report, conformance.CheckDel, "1.0.0")).To(Equal(conformance.Pass))
+		Expect(statusOf(report, conformance.CheckDelIdempotent, "1.0.0")).To(Equal(conformance.Fail))
+		Expect(statusOf(report, conformance.CheckDelMissingNetns, "1.0.0")).To(Equal(conformance.Pass))
+	})
+
+	It("fails a plugin whose DEL needs the namespace", func() {
+		plugin.netnsDel = true
+		report, err := suite.Run(context.TODO())
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(statusOf(report, conformance.CheckDel, "1.0.0")).To(Equal(conformance.Pass))
+		Expect(statusOf(report, conformance.CheckDelMissingNetns, "1.0.0")).To(Equal(conformance.Fail))
+		Expect(plugin.removedNetns).To(HaveLen(3))
+		Expect(plugin.attached).To(HaveLen(3))
+	})
+
+	It("skips the missing namespace check if no namespace can be created", func() {
+		suite.NewNetNS = func() (string, func() error, error) {
+			return "", nil, errors.New("permission denied")
+		}
+		report, err := suite.Run(context.TODO())
+		Expect(err).NotTo(HaveOccurred())
+
+		res, _ := report.Get(conformance.CheckDelMissingNetns, "1.0.0")
+		Expect(res.Status).To(Equal(conformance.Skip))
+		Expect(res.Message).To(Equal("failed to create network namespace: permission denied"))
+	})
+
+	It("fails a plugin not printing errors as JSON", func() {
+		exec.rawErrors = true
+		report, err := suite.Run(context.TODO())
+		Expect(err).NotTo(HaveOccurred())
+
+		res, _ := report.Get(conformance.CheckErrorJSON, "1.0.0")
+		Expect(res.Status).To(Equal(conformance.Fail))
+		Expect(res.Message).To(ContainSubstring("did not print an error as JSON"))
+	})
+
+	It("creates a namespace per version if none is given", func() {
+		var created, removed int
+		suite.Netns = ""
+		suite.NewNetNS = func() (string, func() error, error) {
+			created++
+			return fmt.Sprintf("/var/run/netns/fake%d", created), func() error { removed++; return nil }, nil
+		}
+		_, err := suite.Run(context.TODO())
+		Expect(err).NotTo(HaveOccurred())
+		// one for the checks and one for the missing namespace check
+		Expect(created).To(Equal(6))
+		Expect(removed).To(Equal(6))
+	})
+
+	It("rejects an invalid plugin configuration", func() {
+		suite.Config = []byte(`{`)
+		_, err := suite.Run(context.TODO())
+		Expect(err).To(MatchError(ContainSubstring("failed to decode plugin configuration")))
+	})
+
+	Describe("the report", func() {
+		It("writes a text matrix with failures", func() {
+			plugin.resultVersion = "1.0.0"
+			suite.Versions = []string{"1.0.0", "1.1.0"}
+			report, err := suite.Run(context.TODO())
+			Expect(err).NotTo(HaveOccurred())
+
+			out := &bytes.Buffer{}
+			Expect(report.WriteText(out)).To(Succeed())
+			Expect(out.String()).To(ContainSubstring("CHECK              1.0.0  1.1.0\n"))
+			Expect(out.String()).To(ContainSubstring("result-version     PASS   FAIL\n"))
+			Expect(out.String()).To(ContainSubstring("gc                 SKIP   PASS\n"))
+			Expect(out.String()).To(ContainSubstring(`result-version (1.1.0): result has cniVersion "1.0.0", expected "1.1.0"`))
+			Expect(out.String()).To(HaveSuffix("\nFAIL\n"))
+		})
+
+		It("writes JSON", func() {
+			suite.Versions = []string{"1.0.0"}
+			report, err := suite.Run(context.TODO())
+			Expect(err).NotTo(HaveOccurred())
+
+			out := &bytes.Buffer{}
+			Expect(report.WriteJSON(out)).To(Succeed())
+			decoded := &conformance.Report{}
+			Expect(json.Unmarshal(out.Bytes(), decoded)).To(Succeed())
+			Expect(decoded).To(Equal(report))
+		})
+	})
+})
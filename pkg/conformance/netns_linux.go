@@ -0,0 +1,37 @@
+// Copyright 2024 CNI authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package conformance
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/containernetworking/cni/pkg/ns"
+)
+
+// defaultNewNetNS creates a named network namespace in the default run
+// directory
+func defaultNewNetNS() (string, func() error, error) {
+	m := ns.NewManager("")
+	name := fmt.Sprintf("cni-conformance-%d-%d", os.Getpid(), time.Now().UnixNano())
+	netns, err := m.Create(name)
+	if err != nil {
+		return "", nil, err
+	}
+	path := netns.Path()
+	netns.Close()
+	return path, func() error { return m.Remove(name) }, nil
+}
@@ -0,0 +1,146 @@
+// Copyright 2024 CNI authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package conformance
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// Status is the outcome of a check
+type Status string
+
+const (
+	Pass Status = "pass"
+	Fail Status = "fail"
+	// Skip means the check does not apply to the version, or could not
+	// run because an earlier check failed
+	Skip Status = "skip"
+)
+
+// CheckResult is the outcome of one check for one CNI version
+type CheckResult struct {
+	Check      string `json:"check"`
+	CNIVersion string `json:"cniVersion"`
+	Status     Status `json:"status"`
+	Message    string `json:"message,omitempty"`
+}
+
+// Report holds the outcome of all checks
+type Report struct {
+	Plugin string `json:"plugin"`
+	// SupportedVersions are the versions the plugin reported
+	SupportedVersions []string `json:"supportedVersions"`
+	// Versions are the versions that were tested
+	Versions []string      `json:"versions"`
+	Results  []CheckResult `json:"results"`
+}
+
+func (r *Report) add(check, v string, status Status, msg string) {
+	r.Results = append(r.Results, CheckResult{Check: check, CNIVersion: v, Status: status, Message: msg})
+}
+
+// addErr records a check that passes if the command returned no error
+func (r *Report) addErr(check, v, command string, err error) {
+	if err != nil {
+		r.add(check, v, Fail, fmt.Sprintf("%s failed: %v", command, err))
+	} else {
+		r.add(check, v, Pass, "")
+	}
+}
+
+// skipRest skips all checks after CheckVersion
+func (r *Report) skipRest(v, msg string) {
+	for _, check := range Checks[1:] {
+		r.add(check, v, Skip, msg)
+	}
+}
+
+// Passed returns true if no check failed
+func (r *Report) Passed() bool {
+	for _, res := range r.Results {
+		if res.Status == Fail {
+			return false
+		}
+	}
+	return true
+}
+
+// Get returns the result of check for version v, if it was run
+func (r *Report) Get(check, v string) (CheckResult, bool) {
+	for _, res := range r.Results {
+		if res.Check == check && res.CNIVersion == v {
+			return res, true
+		}
+	}
+	return CheckResult{}, false
+}
+
+// WriteJSON writes the report as JSON
+func (r *Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "    ")
+	return enc.Encode(r)
+}
+
+// WriteText writes the report as a matrix of checks and versions, followed
+// by the messages of failed checks
+func (r *Report) WriteText(w io.Writer) error {
+	fmt.Fprintf(w, "Plugin: %s\n", r.Plugin)
+	fmt.Fprintf(w, "Supported versions: %s\n\n", strings.Join(r.SupportedVersions, ", "))
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "CHECK\t%s\n", strings.Join(r.Versions, "\t"))
+	for _, check := range Checks {
+		cells := make([]string, len(r.Versions))
+		for i, v := range r.Versions {
+			cells[i] = "-"
+			if res, ok := r.Get(check, v); ok {
+				cells[i] = strings.ToUpper(string(res.Status))
+			}
+		}
+		fmt.Fprintf(tw, "%s\t%s\n", check, strings.Join(cells, "\t"))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	var failures []CheckResult
+	for _, res := range r.Results {
+		if res.Status == Fail {
+			failures = append(failures, res)
+		}
+	}
+	if len(failures) > 0 {
+		fmt.Fprintf(w, "\nFailures:\n")
+		for _, res := range failures {
+			if res.CNIVersion == "" {
+				fmt.Fprintf(w, "  %s: %s\n", res.Check, res.Message)
+			} else {
+				fmt.Fprintf(w, "  %s (%s): %s\n", res.Check, res.CNIVersion, res.Message)
+			}
+		}
+	}
+
+	result := "PASS"
+	if !r.Passed() {
+		result = "FAIL"
+	}
+	_, err := fmt.Fprintf(w, "\n%s\n", result)
+	return err
+}
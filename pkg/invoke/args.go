@@ -15,11 +15,18 @@
 package invoke
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"strings"
+	"time"
 )
 
+// DeadlineEnvVar is the environment variable through which a plugin is told
+// when the runtime will give up on it, as an RFC 3339 timestamp. It is unset
+// or empty when there is no deadline.
+const DeadlineEnvVar = "CNI_DEADLINE"
+
 type CNIArgs interface {
 	// For use with os/exec; i.e., return nil to inherit the
 	// environment from this process
@@ -51,6 +58,9 @@ type Args struct {
 	IfName        string
 	/*插件查询路径列表，按':'进行划分*/
 	Path          string
+	// Deadline, if set, is passed to the plugin in CNI_DEADLINE. The
+	// ExecPlugin* functions set it from the deadline of their context.
+	Deadline time.Time
 }
 
 // Args implements the CNIArgs interface
@@ -82,9 +92,54 @@ func (args *Args) AsEnv() []string {
 		/*CNI插件路径查询列表*/
 		"CNI_PATH="+args.Path,
 	)
+	if args.Deadline.IsZero() {
+		// Don't pass on a deadline of this process
+		env = unsetEnv(env, DeadlineEnvVar)
+	} else {
+		env = append(env, DeadlineEnvVar+"="+formatDeadline(args.Deadline))
+	}
 	return dedupEnv(env)
 }
 
+func formatDeadline(deadline time.Time) string {
+	return deadline.UTC().Format(time.RFC3339Nano)
+}
+
+// unsetEnv returns env without the variable key
+func unsetEnv(env []string, key string) []string {
+	out := env[:0:0]
+	for _, kv := range env {
+		if !strings.HasPrefix(kv, key+"=") {
+			out = append(out, kv)
+		}
+	}
+	return out
+}
+
+// argsWithDeadline returns args with the deadline of ctx, unless args
+// already has an earlier one
+func argsWithDeadline(ctx context.Context, args CNIArgs) CNIArgs {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return args
+	}
+	switch a := args.(type) {
+	case *Args:
+		if a.Deadline.IsZero() || deadline.Before(a.Deadline) {
+			newArgs := *a
+			newArgs.Deadline = deadline
+			return &newArgs
+		}
+	case *DelegateArgs:
+		if a.Deadline.IsZero() || deadline.Before(a.Deadline) {
+			newArgs := *a
+			newArgs.Deadline = deadline
+			return &newArgs
+		}
+	}
+	return args
+}
+
 // taken from rkt/networking/net_plugin.go
 func stringify(pluginArgs [][2]string) string {
 	entries := make([]string, len(pluginArgs))
@@ -103,6 +158,9 @@ var _ CNIArgs = &DelegateArgs{}
 
 type DelegateArgs struct {
 	Command string
+	// Deadline, if set, overrides the CNI_DEADLINE inherited from the
+	// environment
+	Deadline time.Time
 }
 
 func (d *DelegateArgs) AsEnv() []string {
@@ -113,6 +171,9 @@ func (d *DelegateArgs) AsEnv() []string {
 	env = append(env,
 		"CNI_COMMAND="+d.Command,
 	)
+	if !d.Deadline.IsZero() {
+		env = append(env, DeadlineEnvVar+"="+formatDeadline(d.Deadline))
+	}
 	return dedupEnv(env)
 }
 
@@ -16,6 +16,7 @@ package invoke_test
 
 import (
 	"os"
+	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -68,6 +69,22 @@ var _ = Describe("CNIArgs AsEnv", func() {
 			Expect(inStringSlice("CNI_PATH=testpath", cniEnvs)).To(BeFalse())
 		})
 
+		It("passes the deadline in CNI_DEADLINE", func() {
+			deadline := time.Date(2024, 5, 6, 7, 8, 9, 123000000, time.FixedZone("CEST", 2*60*60))
+			args := invoke.Args{Command: "ADD", Deadline: deadline}
+			Expect(inStringSlice("CNI_DEADLINE=2024-05-06T05:08:09.123Z", args.AsEnv())).To(BeTrue())
+		})
+
+		It("does not pass on a deadline from the environment", func() {
+			os.Setenv("CNI_DEADLINE", "2024-05-06T05:08:09Z")
+			defer os.Unsetenv("CNI_DEADLINE")
+
+			args := invoke.Args{Command: "ADD"}
+			for _, env := range args.AsEnv() {
+				Expect(env).NotTo(HavePrefix("CNI_DEADLINE="))
+			}
+		})
+
 		AfterEach(func() {
 			os.Unsetenv("CNI_COMMAND")
 			os.Unsetenv("CNI_IFNAME")
@@ -114,6 +131,17 @@ var _ = Describe("CNIArgs AsEnv", func() {
 			Expect(inStringSlice("CNI_COMMAND=ADD", cniEnvs)).To(BeTrue())
 		})
 
+		It("inherits the deadline unless it has its own", func() {
+			os.Setenv("CNI_DEADLINE", "2024-05-06T05:08:09Z")
+			defer os.Unsetenv("CNI_DEADLINE")
+
+			delegateArgs := invoke.DelegateArgs{Command: "ADD"}
+			Expect(inStringSlice("CNI_DEADLINE=2024-05-06T05:08:09Z", delegateArgs.AsEnv())).To(BeTrue())
+
+			delegateArgs.Deadline = time.Date(2024, 5, 6, 5, 8, 0, 0, time.UTC)
+			Expect(inStringSlice("CNI_DEADLINE=2024-05-06T05:08:00Z", delegateArgs.AsEnv())).To(BeTrue())
+		})
+
 		AfterEach(func() {
 			os.Unsetenv("CNI_COMMAND")
 		})
@@ -17,6 +17,7 @@ package invoke
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 
@@ -130,6 +131,7 @@ func ExecPluginWithResult(ctx context.Context, pluginPath string, netconf []byte
 	}
 
 	/*直接运行pluginPath对应的文件，错误stdout,及err*/
+	args = argsWithDeadline(ctx, args)
 	stdoutBytes, err := exec.ExecPlugin(ctx, pluginPath/*插件路径*/, netconf/*输入的网络配置JSon串*/, args.AsEnv()/*环境变量*/)
 	if err != nil {
 		/*执行失败*/
@@ -150,6 +152,7 @@ func ExecPluginWithoutResult(ctx context.Context, pluginPath string, netconf []b
 	if exec == nil {
 		exec = defaultExec
 	}
+	args = argsWithDeadline(ctx, args)
 	_, err := exec.ExecPlugin(ctx, pluginPath, netconf, args.AsEnv())
 	return err
 }
@@ -173,7 +176,7 @@ func GetVersionInfo(ctx context.Context, pluginPath string, exec Exec) (version.
 	stdin := []byte(fmt.Sprintf(`{"cniVersion":%q}`, version.Current()))
 	stdoutBytes, err := exec.ExecPlugin(ctx, pluginPath, stdin, args.AsEnv())
 	if err != nil {
-		if err.Error() == "unknown CNI_COMMAND: VERSION" {
+		if isUnknownVersionCommand(err) {
 			return version.PluginSupports("0.1.0"), nil
 		}
 		return nil, err
@@ -182,6 +185,18 @@ func GetVersionInfo(ctx context.Context, pluginPath string, exec Exec) (version.
 	return exec.Decode(stdoutBytes)
 }
 
+// isUnknownVersionCommand returns true if err is how plugins older than
+// the VERSION command fail it. The message is compared without the stderr
+// output ExecError adds.
+func isUnknownVersionCommand(err error) bool {
+	const msg = "unknown CNI_COMMAND: VERSION"
+	var e *types.Error
+	if errors.As(err, &e) {
+		return e.Msg == msg
+	}
+	return err.Error() == msg
+}
+
 // DefaultExec is an object that implements the Exec interface which looks
 // for and executes plugins from disk.
 type DefaultExec struct {
@@ -18,12 +18,14 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 
 	"github.com/containernetworking/cni/pkg/invoke"
 	"github.com/containernetworking/cni/pkg/invoke/fakes"
+	"github.com/containernetworking/cni/pkg/types"
 	current "github.com/containernetworking/cni/pkg/types/100"
 	"github.com/containernetworking/cni/pkg/version"
 )
@@ -80,6 +82,32 @@ var _ = Describe("Executing a plugin, unit tests", func() {
 			Expect(rawExec.ExecPluginCall.Received.Environ).To(Equal([]string{"SOME=ENV"}))
 		})
 
+		It("passes the deadline of the context to the plugin", func() {
+			deadline := time.Now().Add(time.Minute)
+			ctx, cancel := context.WithDeadline(ctx, deadline)
+			defer cancel()
+
+			args := &invoke.Args{Command: "ADD"}
+			_, err := invoke.ExecPluginWithResult(ctx, pluginPath, netconf, args, pluginExec)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(rawExec.ExecPluginCall.Received.Environ).To(ContainElement(
+				"CNI_DEADLINE=" + deadline.UTC().Format(time.RFC3339Nano)))
+			// the caller's args are not modified
+			Expect(args.Deadline).To(BeZero())
+		})
+
+		It("keeps an earlier deadline of the args", func() {
+			ctx, cancel := context.WithTimeout(ctx, time.Hour)
+			defer cancel()
+
+			deadline := time.Now().Add(time.Minute)
+			args := &invoke.Args{Command: "ADD", Deadline: deadline}
+			_, err := invoke.ExecPluginWithResult(ctx, pluginPath, netconf, args, pluginExec)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(rawExec.ExecPluginCall.Received.Environ).To(ContainElement(
+				"CNI_DEADLINE=" + deadline.UTC().Format(time.RFC3339Nano)))
+		})
+
 		Context("when the rawExec fails", func() {
 			BeforeEach(func() {
 				rawExec.ExecPluginCall.Returns.Error = errors.New("banana")
@@ -201,6 +229,17 @@ var _ = Describe("Executing a plugin, unit tests", func() {
 				Expect(versionInfo.SupportedVersions()).To(ConsistOf("0.1.0"))
 			})
 
+			It("recognizes the error when the plugin also wrote to stderr", func() {
+				rawExec.ExecPluginCall.Returns.Error = &invoke.ExecError{
+					Err:        types.NewError(types.ErrInvalidEnvironmentVariables, "unknown CNI_COMMAND: VERSION", ""),
+					ExitStatus: 1,
+					Stderr:     []byte("some stderr message"),
+				}
+				versionInfo, err := invoke.GetVersionInfo(ctx, pluginPath, pluginExec)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(versionInfo.SupportedVersions()).To(ConsistOf("0.1.0"))
+			})
+
 			It("sets dummy values for env vars required by very old plugins", func() {
 				_, err := invoke.GetVersionInfo(ctx, pluginPath, pluginExec)
 				Expect(err).NotTo(HaveOccurred())
@@ -0,0 +1,465 @@
+// Copyright 2024 CNI authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fakes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/containernetworking/cni/pkg/invoke"
+	"github.com/containernetworking/cni/pkg/types"
+	current "github.com/containernetworking/cni/pkg/types/100"
+	"github.com/containernetworking/cni/pkg/types/create"
+	"github.com/containernetworking/cni/pkg/version"
+)
+
+// PluginExec is an invoke.Exec that simulates plugins in memory instead of
+// executing binaries. Each simulated plugin keeps track of the attachments
+// it was added to, and can be scripted with faults. All calls are logged in
+// order.
+//
+// By default, a plugin that gets no prevResult returns a result with an
+// interface named after CNI_IFNAME in CNI_NETNS and an address from
+// 10.0.0.0/24, and a plugin that gets a prevResult returns it unchanged.
+// CHECK fails for attachments that were not added, DEL of unknown
+// attachments succeeds, and GC removes the attachments that are not in
+// cni.dev/valid-attachments.
+type PluginExec struct {
+	version.PluginDecoder
+
+	// AutoAdd, if set, simulates plugins of any name, adding them on their
+	// first call
+	AutoAdd bool
+
+	mu      sync.Mutex
+	plugins map[string]*Plugin
+	calls   []*PluginCall
+	faults  []*Fault
+}
+
+// PluginCall is a call to a simulated plugin
+type PluginCall struct {
+	// Plugin is the name of the plugin
+	Plugin string
+	// Network is the network name in the configuration
+	Network     string
+	Command     string
+	ContainerID string
+	NetNS       string
+	IfName      string
+	Args        string
+	Path        string
+	Stdin       []byte
+	// Stdout is what the plugin returned on success, and Err the error it
+	// returned otherwise
+	Stdout []byte
+	Err    error
+}
+
+// Attachment is an attachment a simulated plugin was added to
+type Attachment struct {
+	ContainerID string
+	IfName      string
+	NetNS       string
+	// Result is the plugin's ADD result
+	Result *current.Result
+}
+
+// Fault changes how simulated plugins handle matching calls
+type Fault struct {
+	// Command, if set, limits the fault to calls with this CNI command
+	Command string
+	// Network, if set, limits the fault to calls for this network
+	Network string
+	// Call, if not 0, limits the fault to the Nth matching call, counting
+	// from 1 and from when the fault is injected
+	Call int
+	// Delay delays the call. The call fails if its context is done first.
+	Delay time.Duration
+	// Hang makes the call block until its context is done
+	Hang bool
+	// Err is returned as the plugin's error
+	Err *types.Error
+
+	calls int
+}
+
+// ResultFunc builds the ADD result of a simulated plugin from the result
+// of the previous plugin, which is nil for the first plugin. It is called
+// without the PluginExec locked, so it may call its methods.
+type ResultFunc func(call *PluginCall, prevResult *current.Result) (*current.Result, error)
+
+// Plugin is a plugin simulated by a PluginExec
+type Plugin struct {
+	// Name is the plugin's type
+	Name string
+	// SupportedVersions are the CNI versions the plugin supports,
+	// version.All by default
+	SupportedVersions []string
+	// Result, if set, builds the plugin's ADD results
+	Result ResultFunc
+
+	exec        *PluginExec
+	attachments map[[2]string]*Attachment
+	faults      []*Fault
+	nextIP      int
+}
+
+// NewPluginExec returns a PluginExec without plugins
+func NewPluginExec() *PluginExec {
+	return &PluginExec{plugins: map[string]*Plugin{}}
+}
+
+// AddPlugin adds a simulated plugin with the given name and returns it
+func (e *PluginExec) AddPlugin(name string) *Plugin {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	p := &Plugin{
+		Name:        name,
+		exec:        e,
+		attachments: map[[2]string]*Attachment{},
+	}
+	e.plugins[name] = p
+	return p
+}
+
+// Plugin returns the simulated plugin with the given name, or nil
+func (e *PluginExec) Plugin(name string) *Plugin {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.plugins[name]
+}
+
+// lookup returns the simulated plugin with the given name, adding it if
+// AutoAdd is set. The caller must hold e.mu.
+func (e *PluginExec) lookup(name string) *Plugin {
+	p := e.plugins[name]
+	if p == nil && e.AutoAdd {
+		p = &Plugin{
+			Name:        name,
+			exec:        e,
+			attachments: map[[2]string]*Attachment{},
+		}
+		e.plugins[name] = p
+	}
+	return p
+}
+
+// Calls returns the calls to all plugins, in order
+func (e *PluginExec) Calls() []*PluginCall {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]*PluginCall(nil), e.calls...)
+}
+
+// Reset forgets the calls and the attachments of all plugins, and removes
+// all faults
+func (e *PluginExec) Reset() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.calls = nil
+	e.faults = nil
+	for _, p := range e.plugins {
+		p.attachments = map[[2]string]*Attachment{}
+		p.faults = nil
+	}
+}
+
+// InjectFault makes all plugins handle matching calls as described by f.
+// Faults injected into a plugin apply first.
+func (e *PluginExec) InjectFault(f Fault) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.faults = append(e.faults, &f)
+}
+
+// InjectFault makes the plugin handle matching calls as described by f
+func (p *Plugin) InjectFault(f Fault) {
+	p.exec.mu.Lock()
+	defer p.exec.mu.Unlock()
+	p.faults = append(p.faults, &f)
+}
+
+// Attachments returns the attachments the plugin is added to, sorted by
+// container ID and interface name
+func (p *Plugin) Attachments() []Attachment {
+	p.exec.mu.Lock()
+	defer p.exec.mu.Unlock()
+	attachments := make([]Attachment, 0, len(p.attachments))
+	for _, a := range p.attachments {
+		attachments = append(attachments, *a)
+	}
+	sort.Slice(attachments, func(i, j int) bool {
+		if attachments[i].ContainerID != attachments[j].ContainerID {
+			return attachments[i].ContainerID < attachments[j].ContainerID
+		}
+		return attachments[i].IfName < attachments[j].IfName
+	})
+	return attachments
+}
+
+// FindInPath returns the plugin's path in the first of paths if it is
+// simulated
+func (e *PluginExec) FindInPath(plugin string, paths []string) (string, error) {
+	if len(paths) == 0 {
+		return "", fmt.Errorf("no paths provided")
+	}
+	e.mu.Lock()
+	p := e.lookup(plugin)
+	e.mu.Unlock()
+	if p == nil {
+		return "", fmt.Errorf("failed to find plugin %q in path %s", plugin, paths)
+	}
+	return filepath.Join(paths[0], plugin), nil
+}
+
+// ExecPlugin runs the simulated plugin named after the base name of
+// pluginPath. Plugin errors are returned as *invoke.ExecError, like
+// invoke.RawExec does.
+func (e *PluginExec) ExecPlugin(ctx context.Context, pluginPath string, stdinData []byte, environ []string) ([]byte, error) {
+	call := &PluginCall{
+		Plugin: filepath.Base(pluginPath),
+		Stdin:  stdinData,
+	}
+	var conf types.NetConf
+	if json.Unmarshal(stdinData, &conf) == nil {
+		call.Network = conf.Name
+	}
+	for _, kv := range environ {
+		k, v, _ := strings.Cut(kv, "=")
+		switch k {
+		case "CNI_COMMAND":
+			call.Command = v
+		case "CNI_CONTAINERID":
+			call.ContainerID = v
+		case "CNI_NETNS":
+			call.NetNS = v
+		case "CNI_IFNAME":
+			call.IfName = v
+		case "CNI_ARGS":
+			call.Args = v
+		case "CNI_PATH":
+			call.Path = v
+		}
+	}
+
+	e.mu.Lock()
+	e.calls = append(e.calls, call)
+	p := e.lookup(call.Plugin)
+	var fault *Fault
+	if p != nil {
+		fault = matchFault(append(append([]*Fault(nil), p.faults...), e.faults...), call)
+	}
+	e.mu.Unlock()
+
+	stdout, err := e.run(ctx, p, fault, call)
+	e.mu.Lock()
+	call.Stdout, call.Err = stdout, err
+	e.mu.Unlock()
+	return stdout, err
+}
+
+func (e *PluginExec) run(ctx context.Context, p *Plugin, fault *Fault, call *PluginCall) ([]byte, error) {
+	if p == nil {
+		return nil, fmt.Errorf("fork/exec %s: no such file or directory", call.Plugin)
+	}
+	if fault != nil {
+		if fault.Hang {
+			<-ctx.Done()
+			return nil, execError(types.NewError(types.ErrInternal, "netplugin failed with no error message", ctx.Err().Error()))
+		}
+		if fault.Delay > 0 {
+			select {
+			case <-ctx.Done():
+				return nil, execError(types.NewError(types.ErrInternal, "netplugin failed with no error message", ctx.Err().Error()))
+			case <-time.After(fault.Delay):
+			}
+		}
+		if fault.Err != nil {
+			return nil, execError(fault.Err)
+		}
+	}
+
+	stdout, err := p.handle(call)
+	if err != nil {
+		return nil, execError(err)
+	}
+	return stdout, nil
+}
+
+func execError(err *types.Error) error {
+	return &invoke.ExecError{Err: err, ExitStatus: 1}
+}
+
+// matchFault returns the first of faults applying to call, if any
+func matchFault(faults []*Fault, call *PluginCall) *Fault {
+	for _, f := range faults {
+		if (f.Command != "" && f.Command != call.Command) || (f.Network != "" && f.Network != call.Network) {
+			continue
+		}
+		f.calls++
+		if f.Call == 0 || f.Call == f.calls {
+			return f
+		}
+	}
+	return nil
+}
+
+// pluginConf is the part of the network configuration plugins look at
+type pluginConf struct {
+	types.NetConf
+	ValidAttachments []struct {
+		ContainerID string `json:"containerID"`
+		IfName      string `json:"ifname"`
+	} `json:"cni.dev/valid-attachments,omitempty"`
+}
+
+func (p *Plugin) supportedVersions() []string {
+	if len(p.SupportedVersions) > 0 {
+		return p.SupportedVersions
+	}
+	return version.All.SupportedVersions()
+}
+
+func (p *Plugin) handle(call *PluginCall) ([]byte, *types.Error) {
+	conf := &pluginConf{}
+	if err := json.Unmarshal(call.Stdin, conf); err != nil {
+		return nil, types.NewError(types.ErrDecodingFailure, "failed to decode network configuration", err.Error())
+	}
+	if conf.CNIVersion == "" {
+		conf.CNIVersion = "0.1.0"
+	}
+
+	if call.Command == "VERSION" {
+		out, _ := json.Marshal(version.PluginSupports(p.supportedVersions()...))
+		return out, nil
+	}
+	supported := false
+	for _, v := range p.supportedVersions() {
+		supported = supported || v == conf.CNIVersion
+	}
+	if !supported {
+		return nil, types.NewError(types.ErrIncompatibleCNIVersion, "incompatible CNI versions", fmt.Sprintf("config is %q, plugin supports %q", conf.CNIVersion, p.supportedVersions()))
+	}
+
+	key := [2]string{call.ContainerID, call.IfName}
+	if call.Command == "ADD" {
+		var prevResult *current.Result
+		if conf.RawPrevResult != nil {
+			prev, err := decodePrevResult(conf.RawPrevResult)
+			if err != nil {
+				return nil, types.NewError(types.ErrDecodingFailure, "failed to decode prevResult", err.Error())
+			}
+			prevResult = prev
+		}
+		resultFunc := p.Result
+		if resultFunc == nil {
+			resultFunc = p.defaultResult
+		}
+		result, err := resultFunc(call, prevResult)
+		if err != nil {
+			return nil, asTypesError(err)
+		}
+		versioned, err := result.GetAsVersion(conf.CNIVersion)
+		if err != nil {
+			return nil, types.NewError(types.ErrIncompatibleCNIVersion, "failed to convert result", err.Error())
+		}
+		out, err := json.Marshal(versioned)
+		if err != nil {
+			return nil, types.NewError(types.ErrInternal, "failed to encode result", err.Error())
+		}
+		p.exec.mu.Lock()
+		defer p.exec.mu.Unlock()
+		p.attachments[key] = &Attachment{
+			ContainerID: call.ContainerID,
+			IfName:      call.IfName,
+			NetNS:       call.NetNS,
+			Result:      result,
+		}
+		return out, nil
+	}
+
+	p.exec.mu.Lock()
+	defer p.exec.mu.Unlock()
+	switch call.Command {
+	case "CHECK":
+		if _, ok := p.attachments[key]; !ok {
+			return nil, types.NewError(types.ErrUnknownContainer, fmt.Sprintf("container %s is not attached on %s", call.ContainerID, call.IfName), "")
+		}
+	case "DEL":
+		delete(p.attachments, key)
+	case "GC":
+		valid := map[[2]string]bool{}
+		for _, a := range conf.ValidAttachments {
+			valid[[2]string{a.ContainerID, a.IfName}] = true
+		}
+		for k := range p.attachments {
+			if !valid[k] {
+				delete(p.attachments, k)
+			}
+		}
+	case "STATUS":
+	default:
+		return nil, types.NewError(types.ErrInvalidEnvironmentVariables, fmt.Sprintf("unknown CNI_COMMAND: %s", call.Command), "")
+	}
+	return nil, nil
+}
+
+func (p *Plugin) defaultResult(call *PluginCall, prevResult *current.Result) (*current.Result, error) {
+	if prevResult != nil {
+		return prevResult, nil
+	}
+	p.exec.mu.Lock()
+	p.nextIP++
+	ip := net.IPv4(10, 0, 0, byte(1+p.nextIP%254))
+	p.exec.mu.Unlock()
+	ifIndex := 0
+	return &current.Result{
+		CNIVersion: current.ImplementedSpecVersion,
+		Interfaces: []*current.Interface{{Name: call.IfName, Sandbox: call.NetNS}},
+		IPs: []*current.IPConfig{{
+			Interface: &ifIndex,
+			Address:   net.IPNet{IP: ip, Mask: net.CIDRMask(24, 32)},
+		}},
+	}, nil
+}
+
+func decodePrevResult(raw map[string]interface{}) (*current.Result, error) {
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+	result, err := create.CreateFromBytes(data)
+	if err != nil {
+		return nil, err
+	}
+	return current.GetResult(result)
+}
+
+func asTypesError(err error) *types.Error {
+	var e *types.Error
+	if errors.As(err, &e) {
+		return e
+	}
+	return types.NewError(types.ErrInternal, err.Error(), "")
+}
@@ -0,0 +1,241 @@
+// Copyright 2016 CNI authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fakes_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/containernetworking/cni/libcni"
+	"github.com/containernetworking/cni/pkg/invoke/fakes"
+	"github.com/containernetworking/cni/pkg/types"
+	current "github.com/containernetworking/cni/pkg/types/100"
+)
+
+var _ = Describe("PluginExec", func() {
+	var (
+		exec   *fakes.PluginExec
+		bridge *fakes.Plugin
+		tuning *fakes.Plugin
+		cniNet *libcni.CNIConfig
+		list   *libcni.NetworkConfigList
+		rt     *libcni.RuntimeConf
+		ctx    context.Context
+	)
+
+	BeforeEach(func() {
+		exec = fakes.NewPluginExec()
+		bridge = exec.AddPlugin("bridge")
+		tuning = exec.AddPlugin("tuning")
+		cacheDir := GinkgoT().TempDir()
+		cniNet = libcni.NewCNIConfigWithCacheDir([]string{"/opt/cni/bin"}, cacheDir, exec)
+
+		var err error
+		list, err = libcni.ConfListFromBytes([]byte(`{
+			"cniVersion": "1.1.0",
+			"name": "some-net",
+			"plugins": [{"type": "bridge"}, {"type": "tuning"}]
+		}`))
+		Expect(err).NotTo(HaveOccurred())
+		rt = &libcni.RuntimeConf{
+			ContainerID: "some-container",
+			NetNS:       "/some/netns",
+			IfName:      "eth0",
+			CacheDir:    cacheDir,
+		}
+		ctx = context.TODO()
+	})
+
+	It("simulates a chain of plugins", func() {
+		r, err := cniNet.AddNetworkList(ctx, list, rt)
+		Expect(err).NotTo(HaveOccurred())
+		result, err := current.GetResult(r)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.Interfaces).To(HaveLen(1))
+		Expect(result.Interfaces[0].Name).To(Equal("eth0"))
+		Expect(result.Interfaces[0].Sandbox).To(Equal("/some/netns"))
+		Expect(result.IPs).To(HaveLen(1))
+		Expect(result.IPs[0].Address.String()).To(Equal("10.0.0.2/24"))
+
+		Expect(bridge.Attachments()).To(HaveLen(1))
+		Expect(tuning.Attachments()).To(HaveLen(1))
+		Expect(tuning.Attachments()[0].ContainerID).To(Equal("some-container"))
+
+		Expect(cniNet.CheckNetworkList(ctx, list, rt)).To(Succeed())
+		Expect(cniNet.DelNetworkList(ctx, list, rt)).To(Succeed())
+		Expect(bridge.Attachments()).To(BeEmpty())
+
+		calls := exec.Calls()
+		Expect(calls).To(HaveLen(6))
+		commands := []string{}
+		for _, c := range calls {
+			commands = append(commands, c.Command+" "+c.Plugin)
+		}
+		Expect(commands).To(Equal([]string{
+			"ADD bridge", "ADD tuning",
+			"CHECK bridge", "CHECK tuning",
+			"DEL tuning", "DEL bridge",
+		}))
+		Expect(calls[0].Path).To(Equal("/opt/cni/bin"))
+		Expect(calls[0].IfName).To(Equal("eth0"))
+	})
+
+	It("fails CHECK for attachments that were not added", func() {
+		err := cniNet.CheckNetworkList(ctx, list, rt)
+		var cniErr *types.Error
+		Expect(errors.As(err, &cniErr)).To(BeTrue())
+		Expect(cniErr.Code).To(Equal(uint(types.ErrUnknownContainer)))
+	})
+
+	It("succeeds to delete unknown attachments", func() {
+		Expect(cniNet.DelNetworkList(ctx, list, rt)).To(Succeed())
+	})
+
+	It("removes the attachments that are not valid on GC", func() {
+		// libcni deletes the cached attachments that are not valid first,
+		// so forget one to check the plugins' GC
+		Expect(cniNet.AddNetworkList(ctx, list, rt)).Error().NotTo(HaveOccurred())
+		Expect(cniNet.RemoveCachedAttachment(list.Name, rt)).To(Succeed())
+
+		other := *rt
+		other.ContainerID = "other-container"
+		Expect(cniNet.AddNetworkList(ctx, list, &other)).Error().NotTo(HaveOccurred())
+		Expect(bridge.Attachments()).To(HaveLen(2))
+
+		Expect(cniNet.GCNetworkList(ctx, list, &libcni.GCArgs{
+			ValidAttachments: []libcni.GCAttachment{{ContainerID: "other-container", IfName: "eth0"}},
+		})).To(Succeed())
+		Expect(bridge.Attachments()).To(HaveLen(1))
+		Expect(bridge.Attachments()[0].ContainerID).To(Equal("other-container"))
+	})
+
+	It("uses the plugin's Result func", func() {
+		tuning.Result = func(call *fakes.PluginCall, prevResult *current.Result) (*current.Result, error) {
+			prevResult.DNS.Domain = "example.com"
+			return prevResult, nil
+		}
+		r, err := cniNet.AddNetworkList(ctx, list, rt)
+		Expect(err).NotTo(HaveOccurred())
+		result, err := current.GetResult(r)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.DNS.Domain).To(Equal("example.com"))
+	})
+
+	It("returns the CNI error wrapped by a failing Result func", func() {
+		tuning.Result = func(call *fakes.PluginCall, prevResult *current.Result) (*current.Result, error) {
+			Expect(exec.Calls()).To(HaveLen(2))
+			return nil, fmt.Errorf("tuning: %w", types.NewError(types.ErrTryAgainLater, "busy", ""))
+		}
+		_, err := cniNet.AddNetworkList(ctx, list, rt)
+		var cniErr *types.Error
+		Expect(errors.As(err, &cniErr)).To(BeTrue())
+		Expect(cniErr.Code).To(Equal(uint(types.ErrTryAgainLater)))
+		Expect(tuning.Attachments()).To(BeEmpty())
+	})
+
+	It("fails for CNI versions the plugin does not support", func() {
+		tuning.SupportedVersions = []string{"0.4.0"}
+		_, err := cniNet.AddNetworkList(ctx, list, rt)
+		var cniErr *types.Error
+		Expect(errors.As(err, &cniErr)).To(BeTrue())
+		Expect(cniErr.Code).To(Equal(uint(types.ErrIncompatibleCNIVersion)))
+
+		versions, err := cniNet.GetVersionInfo(ctx, "tuning")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(versions.SupportedVersions()).To(Equal([]string{"0.4.0"}))
+	})
+
+	It("fails to find plugins that are not simulated", func() {
+		_, err := exec.FindInPath("missing", []string{"/opt/cni/bin"})
+		Expect(err).To(MatchError(`failed to find plugin "missing" in path [/opt/cni/bin]`))
+	})
+
+	It("simulates plugins of any name with AutoAdd", func() {
+		exec.AutoAdd = true
+		path, err := exec.FindInPath("macvlan", []string{"/opt/cni/bin"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(path).To(Equal("/opt/cni/bin/macvlan"))
+		Expect(exec.Plugin("macvlan")).NotTo(BeNil())
+	})
+
+	Context("with faults", func() {
+		It("fails the Nth matching call with the fault's error", func() {
+			tuning.InjectFault(fakes.Fault{
+				Command: "ADD",
+				Call:    2,
+				Err:     types.NewError(types.ErrTryAgainLater, "busy", ""),
+			})
+			_, err := cniNet.AddNetworkList(ctx, list, rt)
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = cniNet.AddNetworkList(ctx, list, rt)
+			var cniErr *types.Error
+			Expect(errors.As(err, &cniErr)).To(BeTrue())
+			Expect(cniErr.Code).To(Equal(uint(types.ErrTryAgainLater)))
+			Expect(cniErr.Msg).To(Equal("busy"))
+
+			_, err = cniNet.AddNetworkList(ctx, list, rt)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(exec.Calls()[3].Err).To(HaveOccurred())
+		})
+
+		It("apply to all plugins when injected into the exec", func() {
+			exec.InjectFault(fakes.Fault{Command: "ADD", Call: 2, Err: types.NewError(types.ErrInternal, "boom", "")})
+			_, err := cniNet.AddNetworkList(ctx, list, rt)
+			Expect(err).To(MatchError(ContainSubstring("boom")))
+			Expect(exec.Calls()[1].Plugin).To(Equal("tuning"))
+		})
+
+		It("only apply to their network", func() {
+			exec.InjectFault(fakes.Fault{Network: "other-net", Err: types.NewError(types.ErrInternal, "boom", "")})
+			_, err := cniNet.AddNetworkList(ctx, list, rt)
+			Expect(err).NotTo(HaveOccurred())
+
+			list.Name = "other-net"
+			_, err = cniNet.AddNetworkList(ctx, list, rt)
+			Expect(err).To(MatchError(ContainSubstring("boom")))
+			Expect(exec.Calls()[2].Network).To(Equal("other-net"))
+		})
+
+		It("delays calls", func() {
+			bridge.InjectFault(fakes.Fault{Delay: 50 * time.Millisecond})
+			start := time.Now()
+			_, err := cniNet.AddNetworkList(ctx, list, rt)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(time.Since(start)).To(BeNumerically(">=", 50*time.Millisecond))
+		})
+
+		It("hangs until the context is done", func() {
+			bridge.InjectFault(fakes.Fault{Command: "DEL", Hang: true})
+			ctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+			defer cancel()
+			err := cniNet.DelNetworkList(ctx, list, rt)
+			Expect(err).To(MatchError(ContainSubstring("context deadline exceeded")))
+		})
+
+		It("are removed by Reset", func() {
+			bridge.InjectFault(fakes.Fault{Err: types.NewError(types.ErrInternal, "boom", "")})
+			exec.Reset()
+			_, err := cniNet.AddNetworkList(ctx, list, rt)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(exec.Calls()).To(HaveLen(2))
+		})
+	})
+})
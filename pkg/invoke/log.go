@@ -0,0 +1,110 @@
+// Copyright 2024 CNI authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package invoke
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// pluginLogger returns logger annotated with the plugin type and the
+// CNI parameters found in the plugin's environment.
+func pluginLogger(logger *slog.Logger, pluginPath string, environ []string) *slog.Logger {
+	pluginType := filepath.Base(pluginPath)
+	for _, ext := range ExecutableFileExtensions {
+		if ext != "" && strings.HasSuffix(pluginType, ext) {
+			pluginType = strings.TrimSuffix(pluginType, ext)
+			break
+		}
+	}
+
+	var command, containerID, ifName string
+	for _, kv := range environ {
+		k, v, ok := strings.Cut(kv, "=")
+		if !ok {
+			continue
+		}
+		switch k {
+		case "CNI_COMMAND":
+			command = v
+		case "CNI_CONTAINERID":
+			containerID = v
+		case "CNI_IFNAME":
+			ifName = v
+		}
+	}
+
+	return logger.With(
+		slog.String("plugin", pluginType),
+		slog.String("command", command),
+		slog.String("containerID", containerID),
+		slog.String("ifname", ifName),
+	)
+}
+
+// lineLogger is an io.Writer that emits every complete line written to it
+// as a log record. Any trailing partial line is emitted by Flush.
+type lineLogger struct {
+	ctx    context.Context
+	logger *slog.Logger
+
+	mu  sync.Mutex
+	buf []byte
+}
+
+func newLineLogger(ctx context.Context, logger *slog.Logger) *lineLogger {
+	return &lineLogger{ctx: ctx, logger: logger}
+}
+
+func (l *lineLogger) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.buf = append(l.buf, p...)
+	for {
+		i := bytes.IndexByte(l.buf, '\n')
+		if i < 0 {
+			break
+		}
+		l.emit(l.buf[:i])
+		l.buf = l.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+// Flush logs any buffered partial line
+func (l *lineLogger) Flush() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if len(l.buf) > 0 {
+		l.emit(l.buf)
+		l.buf = nil
+	}
+}
+
+func (l *lineLogger) emit(line []byte) {
+	line = bytes.TrimRight(line, "\r")
+	if len(line) == 0 {
+		return
+	}
+	// Use a context that is never cancelled so that lines written while
+	// the plugin is being killed are not dropped by context-aware handlers.
+	l.logger.Log(context.WithoutCancel(l.ctx), slog.LevelInfo, string(line), slog.String("stream", "stderr"))
+}
@@ -18,8 +18,10 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"os/exec"
 	"strings"
 	"time"
@@ -27,13 +29,37 @@ import (
 	"github.com/containernetworking/cni/pkg/types"
 )
 
+// maxStderrTail bounds how much of a failed plugin's stderr is kept in
+// the returned error, and maxStderrMessage how much of it is included in
+// the error message
+const (
+	maxStderrTail    = 4096
+	maxStderrMessage = 512
+)
+
 type RawExec struct {
 	/*仅需要指定stderr*/
 	Stderr io.Writer
+
+	// Logger, if set, receives each line the plugin writes to stderr as a
+	// log record annotated with the plugin type, CNI command, container ID
+	// and interface name. When Logger is set, Stderr is not written to.
+	Logger *slog.Logger
+
+	// Verifier, if set, is consulted before each plugin is executed and
+	// any plugin binary it rejects is not run. The plugin is executed by
+	// path after it was verified; see PluginVerifier for what this means.
+	Verifier *PluginVerifier
 }
 
 /*RawExec对象通过pluginPath直接运行插件，并向其提供输入的json串及环境变量，返回其*/
 func (e *RawExec) ExecPlugin(ctx context.Context, pluginPath string/*插件路径*/, stdinData []byte/*输入的json串*/, environ []string) ([]byte, error) {
+	if e.Verifier != nil {
+		if err := e.Verifier.Verify(pluginPath); err != nil {
+			return nil, fmt.Errorf("refusing to execute plugin: %w", err)
+		}
+	}
+
 	stdout := &bytes.Buffer{}
 	stderr := &bytes.Buffer{}
 	c := exec.CommandContext(ctx, pluginPath)
@@ -44,6 +70,13 @@ func (e *RawExec) ExecPlugin(ctx context.Context, pluginPath string/*插件路
 	c.Stdout = stdout
 	c.Stderr = stderr
 
+	var stderrLog *lineLogger
+	if e.Logger != nil {
+		stderrLog = newLineLogger(ctx, pluginLogger(e.Logger, pluginPath, environ))
+		defer stderrLog.Flush()
+		c.Stderr = io.MultiWriter(stderr, stderrLog)
+	}
+
 	// Retry the command on "text file busy" errors
 	for i := 0; i <= 5; i++ {
 		/*执行此插件*/
@@ -68,7 +101,7 @@ func (e *RawExec) ExecPlugin(ctx context.Context, pluginPath string/*插件路
 	// Copy stderr to caller's buffer in case plugin printed to both
 	// stdout and stderr for some reason. Ignore failures as stderr is
 	// only informational.
-	if e.Stderr != nil && stderr.Len() > 0 {
+	if e.Logger == nil && e.Stderr != nil && stderr.Len() > 0 {
 		_, _ = stderr.WriteTo(e.Stderr)
 	}
 	
@@ -76,8 +109,58 @@ func (e *RawExec) ExecPlugin(ctx context.Context, pluginPath string/*插件路
 	return stdout.Bytes(), nil
 }
 
+// ExecError is returned by RawExec when a plugin does not exit successfully.
+// It wraps the error the plugin reported and keeps the last part of the
+// plugin's stderr output for diagnostics.
+//
+// RawExec used to return the plugin's *types.Error directly. Callers that
+// type-assert err.(*types.Error) must use errors.As instead, which finds
+// it through Unwrap.
+type ExecError struct {
+	// Err is the error printed by the plugin on stdout, or one describing
+	// why the plugin failed if it did not print a valid error
+	Err *types.Error
+	// ExitStatus is the plugin's exit code, or -1 if the plugin could not
+	// be started or was terminated by a signal
+	ExitStatus int
+	// Stderr holds at most the last 4KiB the plugin wrote to stderr
+	Stderr []byte
+
+	// stderrInMsg is set when Err's message already quotes Stderr
+	stderrInMsg bool
+}
+
+// Error returns the plugin's error followed by at most the last 512 bytes
+// of its stderr output
+func (e *ExecError) Error() string {
+	stderr := bytes.TrimSpace(e.Stderr)
+	if len(stderr) == 0 || e.stderrInMsg {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("%s; stderr: %q", e.Err.Error(), tail(stderr, maxStderrMessage))
+}
+
+func (e *ExecError) Unwrap() error {
+	return e.Err
+}
+
+// tail returns the last max bytes of data, starting on a line boundary
+// where possible
+func tail(data []byte, max int) []byte {
+	if len(data) <= max {
+		return data
+	}
+	t := data[len(data)-max:]
+	if i := bytes.IndexByte(t, '\n'); i >= 0 && i < len(t)-1 {
+		t = t[i+1:]
+	}
+	return t
+}
+
 func (e *RawExec) pluginErr(err error, stdout, stderr []byte) error {
 	emsg := types.Error{}
+	stderr = append([]byte(nil), tail(stderr, maxStderrTail)...)
+	stderrInMsg := false
 	if len(stdout) == 0 {
 		if len(stderr) == 0 {
 			/*标准输出及标准错误输出均无内容*/
@@ -85,12 +168,18 @@ func (e *RawExec) pluginErr(err error, stdout, stderr []byte) error {
 		} else {
 			/*标准输出无内容，但标准错误输出有内容*/
 			emsg.Msg = fmt.Sprintf("netplugin failed: %q", string(stderr))
+			stderrInMsg = true
 		}
 	} else if perr := json.Unmarshal(stdout, &emsg); perr != nil {
 		/*标准输出有内容，标准错误输出将被忽略，但在格式化输出时出错*/
 		emsg.Msg = fmt.Sprintf("netplugin failed but error parsing its diagnostic message %q: %v", string(stdout), perr)
 	}
-	return &emsg
+	exitStatus := -1
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		exitStatus = exitErr.ExitCode()
+	}
+	return &ExecError{Err: &emsg, ExitStatus: exitStatus, Stderr: stderr, stderrInMsg: stderrInMsg}
 }
 
 /*在paths列表中查找plugin,获得其绝对路径*/
@@ -17,12 +17,18 @@ package invoke_test
 import (
 	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
 	"os"
+	"path/filepath"
+	"strings"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 
 	"github.com/containernetworking/cni/pkg/invoke"
+	"github.com/containernetworking/cni/pkg/types"
 	noop_debug "github.com/containernetworking/cni/plugins/test/noop/debug"
 )
 
@@ -101,6 +107,81 @@ var _ = Describe("RawExec", func() {
 		})
 	})
 
+	Context("when the Logger is set", func() {
+		var (
+			logBuffer    *bytes.Buffer
+			stderrBuffer *bytes.Buffer
+		)
+
+		BeforeEach(func() {
+			logBuffer = &bytes.Buffer{}
+			stderrBuffer = &bytes.Buffer{}
+			execer.Stderr = stderrBuffer
+			execer.Logger = slog.New(slog.NewJSONHandler(logBuffer, nil))
+			debug.ReportStderr = "first line\nsecond line"
+			Expect(debug.WriteDebug(debugFileName)).To(Succeed())
+		})
+
+		It("logs each stderr line annotated with the invocation", func() {
+			_, err := execer.ExecPlugin(ctx, pathToPlugin, stdin, environ)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(stderrBuffer.Len()).To(BeZero())
+
+			lines := strings.Split(strings.TrimSpace(logBuffer.String()), "\n")
+			Expect(lines).To(HaveLen(2))
+			for i, msg := range []string{"first line", "second line"} {
+				var record map[string]interface{}
+				Expect(json.Unmarshal([]byte(lines[i]), &record)).To(Succeed())
+				Expect(record).To(HaveKeyWithValue("msg", msg))
+				Expect(record).To(HaveKeyWithValue("plugin", filepath.Base(pathToPlugin)))
+				Expect(record).To(HaveKeyWithValue("command", "ADD"))
+				Expect(record).To(HaveKeyWithValue("containerID", "some-container-id"))
+				Expect(record).To(HaveKeyWithValue("ifname", "some-eth0"))
+			}
+		})
+
+		It("logs stderr lines when the plugin fails", func() {
+			debug.ReportResult = ""
+			debug.ExitWithCode = 1
+			Expect(debug.WriteDebug(debugFileName)).To(Succeed())
+
+			_, err := execer.ExecPlugin(ctx, pathToPlugin, stdin, environ)
+			Expect(err).To(HaveOccurred())
+			Expect(logBuffer.String()).To(ContainSubstring(`"msg":"first line"`))
+			Expect(logBuffer.String()).To(ContainSubstring(`"msg":"second line"`))
+		})
+	})
+
+	Context("when a Verifier is set", func() {
+		var digest string
+
+		BeforeEach(func() {
+			contents, err := os.ReadFile(pathToPlugin)
+			Expect(err).NotTo(HaveOccurred())
+			digest = sha256Hex(contents)
+		})
+
+		It("runs a plugin matching the manifest", func() {
+			execer.Verifier = invoke.NewPluginVerifier(map[string]string{pathToPlugin: digest})
+			execer.Verifier.SkipPermissionCheck = true
+
+			resultBytes, err := execer.ExecPlugin(ctx, pathToPlugin, stdin, environ)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resultBytes).To(BeEquivalentTo(reportResult))
+		})
+
+		It("refuses to run a plugin which fails verification", func() {
+			execer.Verifier = invoke.NewPluginVerifier(map[string]string{})
+
+			_, err := execer.ExecPlugin(ctx, pathToPlugin, stdin, environ)
+			Expect(err).To(MatchError(ContainSubstring("refusing to execute plugin")))
+
+			debug, err := noop_debug.ReadDebug(debugFileName)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(debug.Command).To(BeEmpty())
+		})
+	})
+
 	Context("when the plugin errors", func() {
 		BeforeEach(func() {
 			debug.ReportResult = ""
@@ -112,7 +193,7 @@ var _ = Describe("RawExec", func() {
 				Expect(debug.WriteDebug(debugFileName)).To(Succeed())
 				_, err := execer.ExecPlugin(ctx, pathToPlugin, stdin, environ)
 				Expect(err).To(HaveOccurred())
-				Expect(err).To(MatchError("banana"))
+				Expect(err).To(MatchError(`banana; stderr: "some stderr message"`))
 			})
 		})
 
@@ -127,6 +208,44 @@ var _ = Describe("RawExec", func() {
 		})
 	})
 
+	Context("when the plugin errors and writes to stderr", func() {
+		BeforeEach(func() {
+			debug.ReportResult = ""
+			debug.ReportError = "banana"
+		})
+
+		It("keeps the stderr output in the returned error", func() {
+			Expect(debug.WriteDebug(debugFileName)).To(Succeed())
+			_, err := execer.ExecPlugin(ctx, pathToPlugin, stdin, environ)
+
+			var execErr *invoke.ExecError
+			Expect(errors.As(err, &execErr)).To(BeTrue())
+			Expect(execErr.Err.Msg).To(Equal("banana"))
+			Expect(execErr.ExitStatus).To(Equal(1))
+			Expect(string(execErr.Stderr)).To(Equal("some stderr message"))
+
+			var typedErr *types.Error
+			Expect(errors.As(err, &typedErr)).To(BeTrue())
+			Expect(typedErr.Msg).To(Equal("banana"))
+		})
+
+		It("bounds the stderr output kept in the error", func() {
+			debug.ReportStderr = strings.Repeat("early line\n", 1000) + "last line\n"
+			Expect(debug.WriteDebug(debugFileName)).To(Succeed())
+			_, err := execer.ExecPlugin(ctx, pathToPlugin, stdin, environ)
+
+			var execErr *invoke.ExecError
+			Expect(errors.As(err, &execErr)).To(BeTrue())
+			Expect(len(execErr.Stderr)).To(BeNumerically("<=", 4096))
+			Expect(string(execErr.Stderr)).To(HavePrefix("early line\n"))
+			Expect(string(execErr.Stderr)).To(HaveSuffix("last line\n"))
+
+			Expect(len(err.Error())).To(BeNumerically("<", 600))
+			Expect(err.Error()).To(HavePrefix(`banana; stderr: "early line\n`))
+			Expect(err.Error()).To(HaveSuffix(`last line"`))
+		})
+	})
+
 	Context("when the plugin errors with no output on stdout or stderr", func() {
 		It("returns the exec error message", func() {
 			debug.ExitWithCode = 1
@@ -0,0 +1,304 @@
+// Copyright 2024 CNI authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package invoke
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/containernetworking/cni/pkg/types"
+	"github.com/containernetworking/cni/pkg/version"
+)
+
+// Redacted replaces the values of redacted keys in recordings
+const Redacted = "REDACTED"
+
+// Recording is a plugin execution captured by a Recorder
+type Recording struct {
+	// Plugin is the path of the plugin binary
+	Plugin string `json:"plugin"`
+	// Env is the plugin's environment: its CNI_ variables and PATH, or
+	// all of it if the Recorder has AllEnv set
+	Env []string `json:"env"`
+	// Stdin and Stdout hold what the plugin read and printed. JSON objects
+	// and arrays are embedded as is, apart from whitespace, and anything
+	// else as a JSON string; use StdinData and StdoutData to get the bytes.
+	Stdin  json.RawMessage `json:"stdin"`
+	Stdout json.RawMessage `json:"stdout,omitempty"`
+	// Stderr is what the plugin wrote to stderr. It is only known in full
+	// if the Recorder executes plugins itself and the plugin succeeded;
+	// otherwise it is the part kept in an ExecError if the plugin failed.
+	Stderr string `json:"stderr,omitempty"`
+	// ExitStatus is the plugin's exit code, or -1 if it is not known
+	ExitStatus int `json:"exitStatus"`
+	// Error is the error the plugin reported, if it failed
+	Error *types.Error `json:"error,omitempty"`
+	// Start is when the plugin was executed and Duration how long it ran
+	Start    time.Time     `json:"start"`
+	Duration time.Duration `json:"duration"`
+}
+
+// Command returns the CNI command the plugin was executed with
+func (r *Recording) Command() string {
+	for i := len(r.Env) - 1; i >= 0; i-- {
+		if k, v, ok := strings.Cut(r.Env[i], "="); ok && k == "CNI_COMMAND" {
+			return v
+		}
+	}
+	return ""
+}
+
+// StdinData returns the bytes the plugin read from stdin
+func (r *Recording) StdinData() []byte {
+	return fromRawData(r.Stdin)
+}
+
+// StdoutData returns the bytes the plugin printed to stdout
+func (r *Recording) StdoutData() []byte {
+	return fromRawData(r.Stdout)
+}
+
+// Recorder is an Exec that records each plugin execution, writing it as a
+// line of JSON to Output. Recordings can be read back with ReadRecordings.
+//
+// Recording never fails a plugin execution; errors writing to Output are
+// ignored.
+//
+// Capture files are meant to be shared, e.g. attached to bug reports, yet
+// hold what plugins read: their configuration and, with AllEnv, the whole
+// environment of the runtime, which often holds credentials such as cloud
+// or proxy tokens. Use Redact for any secrets the capture would include.
+type Recorder struct {
+	// Exec executes the plugins. If nil, the Recorder executes plugins
+	// itself, like RawExec, and records all of their stderr.
+	Exec Exec
+	// Stderr receives the plugins' stderr output when Exec is nil
+	Stderr io.Writer
+	// Verifier, if set, checks plugin binaries before they are executed
+	// when Exec is nil, like RawExec.Verifier
+	Verifier *PluginVerifier
+	// Output receives the recordings
+	Output io.Writer
+	// Redact lists keys whose values are replaced with Redacted in the
+	// recordings: keys of the JSON read and printed by plugins, at any
+	// depth, environment variables, and CNI_ARGS keys. Keys match
+	// regardless of case. Redacted recordings cannot be replayed
+	// faithfully, so only redact what must not be captured.
+	Redact []string
+	// AllEnv records the plugins' whole environment instead of only their
+	// CNI_ variables and PATH. The whole environment likely holds secrets.
+	AllEnv bool
+
+	mu sync.Mutex
+}
+
+var _ Exec = &Recorder{}
+
+func (r *Recorder) ExecPlugin(ctx context.Context, pluginPath string, stdinData []byte, environ []string) ([]byte, error) {
+	stderr := &bytes.Buffer{}
+	execPlugin := func() ([]byte, error) {
+		if r.Exec != nil {
+			return r.Exec.ExecPlugin(ctx, pluginPath, stdinData, environ)
+		}
+		var w io.Writer = stderr
+		if r.Stderr != nil {
+			w = io.MultiWriter(stderr, r.Stderr)
+		}
+		raw := &RawExec{Stderr: w, Verifier: r.Verifier}
+		return raw.ExecPlugin(ctx, pluginPath, stdinData, environ)
+	}
+
+	start := time.Now()
+	stdout, err := execPlugin()
+	rec := &Recording{
+		Plugin:   pluginPath,
+		Env:      r.redactEnv(environ),
+		Stdin:    toRawData(r.redactJSON(stdinData)),
+		Start:    start,
+		Duration: time.Since(start),
+	}
+	if len(stdout) > 0 {
+		rec.Stdout = toRawData(r.redactJSON(stdout))
+	}
+	if err != nil {
+		rec.ExitStatus = -1
+		var execErr *ExecError
+		if errors.As(err, &execErr) {
+			rec.ExitStatus = execErr.ExitStatus
+			if stderr.Len() == 0 {
+				stderr.Write(execErr.Stderr)
+			}
+		}
+		var cniErr *types.Error
+		if !errors.As(err, &cniErr) {
+			cniErr = types.NewError(types.ErrInternal, err.Error(), "")
+		}
+		rec.Error = cniErr
+	}
+	rec.Stderr = stderr.String()
+
+	if r.Output != nil {
+		if data, marshalErr := json.Marshal(rec); marshalErr == nil {
+			r.mu.Lock()
+			_, _ = r.Output.Write(append(data, '\n'))
+			r.mu.Unlock()
+		}
+	}
+	return stdout, err
+}
+
+func (r *Recorder) FindInPath(plugin string, paths []string) (string, error) {
+	if r.Exec != nil {
+		return r.Exec.FindInPath(plugin, paths)
+	}
+	return FindInPath(plugin, paths)
+}
+
+func (r *Recorder) Decode(jsonBytes []byte) (version.PluginInfo, error) {
+	if r.Exec != nil {
+		return r.Exec.Decode(jsonBytes)
+	}
+	decoder := &version.PluginDecoder{}
+	return decoder.Decode(jsonBytes)
+}
+
+func (r *Recorder) redacted(key string) bool {
+	for _, k := range r.Redact {
+		if strings.EqualFold(k, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactJSON returns data with the values of redacted keys replaced. Data
+// that is not a JSON object or array is returned unchanged.
+func (r *Recorder) redactJSON(data []byte) []byte {
+	if len(r.Redact) == 0 {
+		return data
+	}
+	// Keep numbers as they are rather than converting them to float64
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil || dec.More() {
+		return data
+	}
+	switch v.(type) {
+	case map[string]interface{}, []interface{}:
+	default:
+		return data
+	}
+	redacted, err := json.Marshal(r.redactValue(v))
+	if err != nil {
+		return data
+	}
+	return redacted
+}
+
+func (r *Recorder) redactValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, val := range v {
+			if r.redacted(k) {
+				v[k] = Redacted
+			} else {
+				v[k] = r.redactValue(val)
+			}
+		}
+	case []interface{}:
+		for i, val := range v {
+			v[i] = r.redactValue(val)
+		}
+	}
+	return v
+}
+
+func (r *Recorder) redactEnv(environ []string) []string {
+	env := make([]string, 0, len(environ))
+	for _, kv := range environ {
+		k, v, ok := strings.Cut(kv, "=")
+		if !r.AllEnv && !strings.HasPrefix(k, "CNI_") && k != "PATH" {
+			continue
+		}
+		switch {
+		case !ok:
+		case r.redacted(k):
+			kv = k + "=" + Redacted
+		case k == "CNI_ARGS" && v != "":
+			pairs := strings.Split(v, ";")
+			for i, pair := range pairs {
+				if argKey, _, ok := strings.Cut(pair, "="); ok && r.redacted(argKey) {
+					pairs[i] = argKey + "=" + Redacted
+				}
+			}
+			kv = k + "=" + strings.Join(pairs, ";")
+		}
+		env = append(env, kv)
+	}
+	return env
+}
+
+// ReadRecordings reads the recordings written by a Recorder
+func ReadRecordings(r io.Reader) ([]*Recording, error) {
+	recordings := []*Recording{}
+	dec := json.NewDecoder(r)
+	for {
+		rec := &Recording{}
+		if err := dec.Decode(rec); err == io.EOF {
+			return recordings, nil
+		} else if err != nil {
+			return nil, err
+		}
+		recordings = append(recordings, rec)
+	}
+}
+
+// ReadRecordingsFile reads the recordings a Recorder wrote to a file
+func ReadRecordingsFile(path string) ([]*Recording, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadRecordings(f)
+}
+
+// toRawData returns data as is if it is a JSON object or array, or else as
+// a JSON string
+func toRawData(data []byte) json.RawMessage {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') && json.Valid(trimmed) {
+		return json.RawMessage(trimmed)
+	}
+	quoted, _ := json.Marshal(string(data))
+	return quoted
+}
+
+// fromRawData reverses toRawData
+func fromRawData(raw json.RawMessage) []byte {
+	var s string
+	if len(raw) > 0 && raw[0] == '"' && json.Unmarshal(raw, &s) == nil {
+		return []byte(s)
+	}
+	return raw
+}
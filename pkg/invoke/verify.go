@@ -0,0 +1,209 @@
+// Copyright 2024 CNI authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package invoke
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PluginVerifier checks plugin binaries against a manifest of SHA-256
+// digests before they are executed. Binaries that are not owned by
+// TrustedUID, or that live in (or are themselves) group- or world-writable
+// locations, are refused as well.
+//
+// Symbolic links are resolved first, so the checks apply to the binary
+// that is actually executed and to the directories leading to it.
+//
+// Verified digests are cached by file identity (device, inode, size and
+// modification time) so a binary is only hashed again after it changed.
+// A PluginVerifier is safe for concurrent use.
+//
+// The binary is verified and then executed by path, so a user who can
+// replace it in between defeats the digest check. The permission checks
+// exist to rule out such users; SkipPermissionCheck leaves that gap open.
+type PluginVerifier struct {
+	// Digests maps a plugin to its expected hex-encoded SHA-256 digest.
+	// Keys are either the absolute path of the binary or its file name
+	// (e.g. "bridge" or "bridge.exe"); the absolute path takes precedence.
+	Digests map[string]string
+
+	// TrustedUID is the user that must own plugin binaries. The zero
+	// value requires binaries to be owned by root.
+	TrustedUID int
+
+	// SkipPermissionCheck disables the ownership and writability checks,
+	// leaving only the digest comparison.
+	SkipPermissionCheck bool
+
+	mu       sync.Mutex
+	verified map[string]verifiedFile
+}
+
+type verifiedFile struct {
+	id     fileID
+	digest string
+}
+
+// fileID identifies one version of a file on disk
+type fileID struct {
+	dev   uint64
+	ino   uint64
+	size  int64
+	mtime time.Time
+}
+
+// NewPluginVerifier returns a PluginVerifier which accepts the plugins
+// listed in digests.
+func NewPluginVerifier(digests map[string]string) *PluginVerifier {
+	return &PluginVerifier{Digests: digests}
+}
+
+// LoadPluginManifest reads a manifest of plugin digests in the format
+// produced by sha256sum(1), i.e. one "<hex digest>  <file>" entry per line.
+// Blank lines and lines starting with '#' are ignored.
+func LoadPluginManifest(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	digests, err := ParsePluginManifest(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse plugin manifest %s: %w", path, err)
+	}
+	return digests, nil
+}
+
+// ParsePluginManifest parses a manifest in the format accepted by
+// LoadPluginManifest.
+func ParsePluginManifest(r io.Reader) (map[string]string, error) {
+	digests := map[string]string{}
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("line %d: expected \"<digest> <file>\"", lineNo)
+		}
+		// sha256sum marks binary mode with a leading '*' on the file name
+		name := strings.TrimPrefix(fields[1], "*")
+		digest := strings.ToLower(fields[0])
+		if b, err := hex.DecodeString(digest); err != nil || len(b) != sha256.Size {
+			return nil, fmt.Errorf("line %d: invalid SHA-256 digest %q", lineNo, fields[0])
+		}
+		digests[name] = digest
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return digests, nil
+}
+
+// Verify returns an error if the plugin binary at pluginPath must not be
+// executed.
+func (v *PluginVerifier) Verify(pluginPath string) error {
+	absPath, err := filepath.Abs(pluginPath)
+	if err != nil {
+		return err
+	}
+
+	expected, ok := v.expectedDigest(absPath)
+	if !ok {
+		return fmt.Errorf("plugin %s is not listed in the plugin manifest", absPath)
+	}
+
+	// Check the file that is executed, not a symlink pointing at it
+	realPath, err := filepath.EvalSymlinks(absPath)
+	if err != nil {
+		return err
+	}
+	fi, err := os.Stat(realPath)
+	if err != nil {
+		return err
+	}
+	if !fi.Mode().IsRegular() {
+		return fmt.Errorf("plugin %s is not a regular file", realPath)
+	}
+
+	if !v.SkipPermissionCheck {
+		if err := checkPluginPermissions(realPath, fi, v.TrustedUID); err != nil {
+			return err
+		}
+	}
+
+	id := fileIdentity(fi)
+	v.mu.Lock()
+	cached, ok := v.verified[absPath]
+	v.mu.Unlock()
+	if ok && cached.id == id && cached.digest == expected {
+		return nil
+	}
+
+	digest, err := fileDigest(realPath)
+	if err != nil {
+		return fmt.Errorf("failed to compute digest of plugin %s: %w", realPath, err)
+	}
+	if digest != expected {
+		return fmt.Errorf("plugin %s has SHA-256 digest %s, expected %s", realPath, digest, expected)
+	}
+
+	v.mu.Lock()
+	if v.verified == nil {
+		v.verified = map[string]verifiedFile{}
+	}
+	v.verified[absPath] = verifiedFile{id: id, digest: digest}
+	v.mu.Unlock()
+
+	return nil
+}
+
+func (v *PluginVerifier) expectedDigest(absPath string) (string, bool) {
+	if d, ok := v.Digests[absPath]; ok {
+		return strings.ToLower(d), true
+	}
+	if d, ok := v.Digests[filepath.Base(absPath)]; ok {
+		return strings.ToLower(d), true
+	}
+	return "", false
+}
+
+func fileDigest(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
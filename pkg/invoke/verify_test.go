@@ -0,0 +1,177 @@
+// Copyright 2024 CNI authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package invoke_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/containernetworking/cni/pkg/invoke"
+)
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+var _ = Describe("PluginVerifier", func() {
+	var (
+		pluginDir  string
+		pluginPath string
+		contents   []byte
+		verifier   *invoke.PluginVerifier
+	)
+
+	BeforeEach(func() {
+		var err error
+		pluginDir, err = os.MkdirTemp("", "cni-verify")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.Chmod(pluginDir, 0o755)).To(Succeed())
+
+		contents = []byte("#!/bin/sh\necho plugin\n")
+		pluginPath = filepath.Join(pluginDir, "some-plugin")
+		Expect(os.WriteFile(pluginPath, contents, 0o755)).To(Succeed())
+
+		verifier = invoke.NewPluginVerifier(map[string]string{
+			"some-plugin": sha256Hex(contents),
+		})
+		verifier.TrustedUID = os.Getuid()
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(pluginDir)).To(Succeed())
+	})
+
+	It("accepts a plugin whose digest matches the manifest", func() {
+		Expect(verifier.Verify(pluginPath)).To(Succeed())
+	})
+
+	It("prefers an entry keyed by the absolute path", func() {
+		verifier.Digests[pluginPath] = strings.Repeat("0", 64)
+		Expect(verifier.Verify(pluginPath)).To(MatchError(ContainSubstring("has SHA-256 digest")))
+	})
+
+	It("rejects a plugin that is not listed in the manifest", func() {
+		verifier.Digests = map[string]string{}
+		Expect(verifier.Verify(pluginPath)).To(MatchError(ContainSubstring("is not listed in the plugin manifest")))
+	})
+
+	It("rejects a plugin whose digest does not match", func() {
+		verifier.Digests["some-plugin"] = sha256Hex([]byte("something else"))
+		Expect(verifier.Verify(pluginPath)).To(MatchError(ContainSubstring("has SHA-256 digest " + sha256Hex(contents))))
+	})
+
+	It("re-checks the digest after the binary changes", func() {
+		Expect(verifier.Verify(pluginPath)).To(Succeed())
+
+		Expect(os.WriteFile(pluginPath, []byte("#!/bin/sh\necho replaced\n"), 0o755)).To(Succeed())
+		later := time.Now().Add(time.Minute)
+		Expect(os.Chtimes(pluginPath, later, later)).To(Succeed())
+
+		Expect(verifier.Verify(pluginPath)).To(MatchError(ContainSubstring("has SHA-256 digest")))
+	})
+
+	Context("when checking permissions", func() {
+		BeforeEach(func() {
+			if runtime.GOOS == "windows" {
+				Skip("file ownership and mode bits are not checked on Windows")
+			}
+		})
+
+		It("rejects a group or world writable plugin", func() {
+			Expect(os.Chmod(pluginPath, 0o777)).To(Succeed())
+			Expect(verifier.Verify(pluginPath)).To(MatchError(ContainSubstring("is group or world writable")))
+		})
+
+		It("rejects a plugin in a group or world writable directory", func() {
+			Expect(os.Chmod(pluginDir, 0o777)).To(Succeed())
+			Expect(verifier.Verify(pluginPath)).To(MatchError(ContainSubstring("plugin directory " + pluginDir + " is group or world writable")))
+		})
+
+		It("checks the target of a symlink rather than the symlink's directory", func() {
+			linkDir := filepath.Join(pluginDir, "trusted")
+			Expect(os.Mkdir(linkDir, 0o755)).To(Succeed())
+			targetDir := filepath.Join(pluginDir, "writable")
+			Expect(os.Mkdir(targetDir, 0o755)).To(Succeed())
+			Expect(os.Chmod(targetDir, 0o777)).To(Succeed())
+			target := filepath.Join(targetDir, "real-plugin")
+			Expect(os.WriteFile(target, contents, 0o755)).To(Succeed())
+			link := filepath.Join(linkDir, "some-plugin")
+			Expect(os.Symlink(target, link)).To(Succeed())
+
+			Expect(verifier.Verify(link)).To(MatchError(ContainSubstring("plugin directory " + targetDir + " is group or world writable")))
+		})
+
+		It("rejects a plugin below a group or world writable directory", func() {
+			subDir := filepath.Join(pluginDir, "sub")
+			Expect(os.Mkdir(subDir, 0o755)).To(Succeed())
+			nested := filepath.Join(subDir, "some-plugin")
+			Expect(os.WriteFile(nested, contents, 0o755)).To(Succeed())
+			Expect(os.Chmod(pluginDir, 0o777)).To(Succeed())
+			Expect(verifier.Verify(nested)).To(MatchError(ContainSubstring("plugin directory " + pluginDir + " is group or world writable")))
+
+			Expect(os.Chmod(pluginDir, 0o777|os.ModeSticky)).To(Succeed())
+			Expect(verifier.Verify(nested)).To(Succeed())
+		})
+
+		It("rejects a plugin not owned by the trusted user", func() {
+			verifier.TrustedUID = os.Getuid() + 1
+			Expect(verifier.Verify(pluginPath)).To(MatchError(ContainSubstring("is owned by uid")))
+		})
+
+		It("skips the checks when SkipPermissionCheck is set", func() {
+			Expect(os.Chmod(pluginDir, 0o777)).To(Succeed())
+			verifier.TrustedUID = os.Getuid() + 1
+			verifier.SkipPermissionCheck = true
+			Expect(verifier.Verify(pluginPath)).To(Succeed())
+		})
+	})
+
+	Describe("ParsePluginManifest", func() {
+		It("parses sha256sum output", func() {
+			digestA := sha256Hex([]byte("a"))
+			digestB := sha256Hex([]byte("b"))
+			manifest := "# plugin digests\n" +
+				digestA + "  bridge\n" +
+				"\n" +
+				strings.ToUpper(digestB) + " */opt/cni/bin/host-local\n"
+
+			digests, err := invoke.ParsePluginManifest(strings.NewReader(manifest))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(digests).To(Equal(map[string]string{
+				"bridge":                  digestA,
+				"/opt/cni/bin/host-local": digestB,
+			}))
+		})
+
+		It("rejects malformed digests", func() {
+			_, err := invoke.ParsePluginManifest(strings.NewReader("abcd  bridge\n"))
+			Expect(err).To(MatchError(`line 1: invalid SHA-256 digest "abcd"`))
+		})
+
+		It("rejects malformed lines", func() {
+			_, err := invoke.ParsePluginManifest(strings.NewReader("bridge\n"))
+			Expect(err).To(MatchError(`line 1: expected "<digest> <file>"`))
+		})
+	})
+})
@@ -0,0 +1,75 @@
+// Copyright 2024 CNI authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris
+// +build darwin dragonfly freebsd linux netbsd openbsd solaris
+
+package invoke
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+func fileIdentity(fi os.FileInfo) fileID {
+	id := fileID{size: fi.Size(), mtime: fi.ModTime()}
+	if st, ok := fi.Sys().(*syscall.Stat_t); ok {
+		id.dev = uint64(st.Dev) //nolint:unconvert // type differs between platforms
+		id.ino = uint64(st.Ino) //nolint:unconvert // type differs between platforms
+	}
+	return id
+}
+
+// checkPluginPermissions refuses plugins which are not owned by trustedUID
+// or which could be replaced by another user: the binary itself and the
+// directory containing it must not be group- or world-writable, and each
+// ancestor directory must be owned by root or trustedUID and must not be
+// group- or world-writable unless it has the sticky bit set, like /tmp.
+// pluginPath must not contain symbolic links.
+func checkPluginPermissions(pluginPath string, fi os.FileInfo, trustedUID int) error {
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return fmt.Errorf("unable to determine owner of plugin %s", pluginPath)
+	}
+	if int(st.Uid) != trustedUID {
+		return fmt.Errorf("plugin %s is owned by uid %d, expected uid %d", pluginPath, st.Uid, trustedUID)
+	}
+	if fi.Mode().Perm()&0o022 != 0 {
+		return fmt.Errorf("plugin %s is group or world writable", pluginPath)
+	}
+
+	dir := filepath.Dir(pluginPath)
+	for parent := dir; ; parent = filepath.Dir(parent) {
+		di, err := os.Stat(parent)
+		if err != nil {
+			return err
+		}
+		dst, ok := di.Sys().(*syscall.Stat_t)
+		if !ok {
+			return fmt.Errorf("unable to determine owner of plugin directory %s", parent)
+		}
+		if dst.Uid != 0 && int(dst.Uid) != trustedUID {
+			return fmt.Errorf("plugin directory %s is owned by uid %d, expected uid 0 or %d", parent, dst.Uid, trustedUID)
+		}
+		writable := di.Mode().Perm()&0o022 != 0
+		if writable && (parent == dir || di.Mode()&os.ModeSticky == 0) {
+			return fmt.Errorf("plugin directory %s is group or world writable", parent)
+		}
+		if parent == filepath.Dir(parent) {
+			return nil
+		}
+	}
+}
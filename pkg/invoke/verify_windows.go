@@ -0,0 +1,27 @@
+// Copyright 2024 CNI authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package invoke
+
+import "os"
+
+func fileIdentity(fi os.FileInfo) fileID {
+	return fileID{size: fi.Size(), mtime: fi.ModTime()}
+}
+
+// File ownership and mode bits do not map onto Windows ACLs, so only the
+// digest is checked on this platform.
+func checkPluginPermissions(_ string, _ os.FileInfo, _ int) error {
+	return nil
+}
@@ -0,0 +1,51 @@
+// Copyright 2024 CNI authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ns
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"golang.org/x/sys/unix"
+)
+
+var _ = Describe("IsNSorErr on kernels without NS_GET_NSTYPE", func() {
+	for _, errno := range []unix.Errno{unix.ENOTTY, unix.EINVAL} {
+		errno := errno
+
+		Context("when the ioctl fails with "+errno.Error(), func() {
+			BeforeEach(func() {
+				orig := getNSType
+				getNSType = func(int) (int, error) { return 0, errno }
+				DeferCleanup(func() { getNSType = orig })
+			})
+
+			It("accepts network namespaces", func() {
+				Expect(IsNSorErr("/proc/self/ns/net")).To(Succeed())
+			})
+
+			It("still rejects plain procfs files", func() {
+				var notNSErr NSPathNotNSErr
+				Expect(errors.As(IsNSorErr("/proc/self/status"), &notNSErr)).To(BeTrue())
+			})
+		})
+	}
+
+	It("accepts procfs network namespace links of kernels before 3.19", func() {
+		Expect(checkNSFallback("/proc/self/ns/net", PROCFS_MAGIC)).To(Succeed())
+		Expect(checkNSFallback("/proc/self/ns/mnt", PROCFS_MAGIC)).NotTo(Succeed())
+	})
+})
@@ -0,0 +1,229 @@
+// Copyright 2024 CNI authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ns
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	"golang.org/x/sys/unix"
+)
+
+// DefaultNetNSRunDir is where iproute2 ("ip netns") pins named network
+// namespaces.
+const DefaultNetNSRunDir = "/var/run/netns"
+
+// Manager creates, lists and removes named network namespaces. A named
+// namespace is kept alive by bind-mounting it onto a file in RunDir, like
+// "ip netns add" does, so namespaces created by a Manager and by iproute2
+// can be used interchangeably.
+//
+// A Manager is safe for concurrent use. Creating the same name from
+// several Managers or processes is also safe: exactly one succeeds.
+type Manager struct {
+	// RunDir is the directory namespaces are pinned in. It defaults to
+	// DefaultNetNSRunDir.
+	RunDir string
+
+	mu sync.Mutex
+}
+
+// NewManager returns a Manager pinning namespaces in runDir, or in
+// DefaultNetNSRunDir if runDir is empty.
+func NewManager(runDir string) *Manager {
+	return &Manager{RunDir: runDir}
+}
+
+func (m *Manager) runDir() string {
+	if m.RunDir == "" {
+		return DefaultNetNSRunDir
+	}
+	return m.RunDir
+}
+
+func validateName(name string) error {
+	if name == "" || name == "." || name == ".." || strings.ContainsRune(name, '/') {
+		return fmt.Errorf("invalid network namespace name %q", name)
+	}
+	return nil
+}
+
+// Path returns the path a namespace with the given name is pinned at.
+func (m *Manager) Path(name string) string {
+	return filepath.Join(m.runDir(), name)
+}
+
+// Create creates a new network namespace, pins it under the given name and
+// returns it. The caller must Close the returned NetNS; the namespace lives
+// on until it is removed with Remove. Create returns an error wrapping
+// os.ErrExist if the name is already in use.
+func (m *Manager) Create(name string) (NetNS, error) {
+	if err := validateName(name); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	runDir := m.runDir()
+	if err := os.MkdirAll(runDir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create network namespace directory %s: %w", runDir, err)
+	}
+	if err := makeShared(runDir); err != nil {
+		return nil, err
+	}
+
+	// Creating the mount point exclusively reserves the name, also against
+	// other processes
+	nsPath := m.Path(name)
+	f, err := os.OpenFile(nsPath, os.O_RDONLY|os.O_CREATE|os.O_EXCL, 0o444)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create network namespace %s: %w", name, err)
+	}
+	f.Close()
+
+	if err := pinNewNS(nsPath); err != nil {
+		_ = unix.Unmount(nsPath, unix.MNT_DETACH)
+		_ = os.Remove(nsPath)
+		return nil, fmt.Errorf("failed to create network namespace %s: %w", name, err)
+	}
+
+	return GetNS(nsPath)
+}
+
+// makeShared makes runDir a shared mount point, so that namespaces pinned
+// in it are visible in mount namespaces created afterwards. Like iproute2,
+// runDir is bind-mounted onto itself first if it is not a mount point.
+func makeShared(runDir string) error {
+	err := unix.Mount("", runDir, "none", unix.MS_SHARED|unix.MS_REC, "")
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, unix.EINVAL) {
+		return fmt.Errorf("failed to make %s a shared mount: %w", runDir, err)
+	}
+	if err := unix.Mount(runDir, runDir, "none", unix.MS_BIND|unix.MS_REC, ""); err != nil {
+		return fmt.Errorf("failed to bind-mount %s: %w", runDir, err)
+	}
+	if err := unix.Mount("", runDir, "none", unix.MS_SHARED|unix.MS_REC, ""); err != nil {
+		return fmt.Errorf("failed to make %s a shared mount: %w", runDir, err)
+	}
+	return nil
+}
+
+// pinNewNS creates a network namespace and bind-mounts it onto nsPath.
+// The namespace is created on a dedicated OS thread, which is discarded
+// if it cannot be switched back to its original namespace.
+func pinNewNS(nsPath string) error {
+	errCh := make(chan error, 1)
+	go func() {
+		runtime.LockOSThread()
+		// Deliberately not unlocked unless the thread is restored below
+
+		origNS, err := GetCurrentNS()
+		if err != nil {
+			errCh <- fmt.Errorf("failed to open current netns: %w", err)
+			runtime.UnlockOSThread()
+			return
+		}
+		defer origNS.Close()
+
+		if err := unix.Unshare(unix.CLONE_NEWNET); err != nil {
+			errCh <- fmt.Errorf("failed to unshare netns: %w", err)
+			runtime.UnlockOSThread()
+			return
+		}
+
+		err = unix.Mount(getCurrentThreadNetNSPath(), nsPath, "none", unix.MS_BIND, "")
+		if err != nil {
+			err = fmt.Errorf("failed to bind-mount netns onto %s: %w", nsPath, err)
+		}
+		if setErr := origNS.Set(); setErr == nil {
+			runtime.UnlockOSThread()
+		}
+		errCh <- err
+	}()
+	return <-errCh
+}
+
+// Get returns the named network namespace. It returns a NSPathNotExistErr
+// if there is no namespace with that name.
+func (m *Manager) Get(name string) (NetNS, error) {
+	if err := validateName(name); err != nil {
+		return nil, err
+	}
+	return GetNS(m.Path(name))
+}
+
+// List returns the sorted names of the namespaces pinned in RunDir. Files
+// in RunDir that are not network namespaces, e.g. left over from an
+// interrupted Create, are skipped.
+func (m *Manager) List() ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entries, err := os.ReadDir(m.runDir())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		if IsNSorErr(m.Path(entry.Name())) == nil {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Remove unpins the named namespace and removes its mount point. The
+// namespace is destroyed once no process uses it anymore. Remove returns
+// an error wrapping os.ErrNotExist if there is no namespace with that
+// name.
+func (m *Manager) Remove(name string) error {
+	if err := validateName(name); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	nsPath := m.Path(name)
+	if _, err := os.Lstat(nsPath); err != nil {
+		return fmt.Errorf("failed to remove network namespace %s: %w", name, err)
+	}
+	// The mount point may not be a mount anymore, e.g. after a reboot with
+	// a persistent RunDir
+	if err := unix.Unmount(nsPath, unix.MNT_DETACH); err != nil && !errors.Is(err, unix.EINVAL) {
+		return fmt.Errorf("failed to unmount network namespace %s: %w", name, err)
+	}
+	if err := os.Remove(nsPath); err != nil {
+		return fmt.Errorf("failed to remove network namespace %s: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+// Copyright 2024 CNI authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ns_test
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"golang.org/x/sys/unix"
+
+	"github.com/containernetworking/cni/pkg/ns"
+)
+
+var _ = Describe("Named network namespaces", func() {
+	var (
+		runDir  string
+		manager *ns.Manager
+	)
+
+	BeforeEach(func() {
+		if os.Geteuid() != 0 {
+			Skip("creating network namespaces requires root")
+		}
+		runDir = filepath.Join(GinkgoT().TempDir(), "netns")
+		manager = ns.NewManager(runDir)
+	})
+
+	AfterEach(func() {
+		if manager == nil {
+			return
+		}
+		names, _ := manager.List()
+		for _, name := range names {
+			_ = manager.Remove(name)
+		}
+		// Undo the bind mount of the run directory onto itself
+		_ = unix.Unmount(runDir, unix.MNT_DETACH)
+	})
+
+	It("creates, lists and removes namespaces", func() {
+		Expect(manager.List()).To(BeEmpty())
+
+		for _, name := range []string{"blue", "red"} {
+			netns, err := manager.Create(name)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(netns.Path()).To(Equal(filepath.Join(runDir, name)))
+			Expect(netns.Close()).To(Succeed())
+		}
+		Expect(manager.List()).To(Equal([]string{"blue", "red"}))
+
+		Expect(manager.Remove("blue")).To(Succeed())
+		Expect(manager.List()).To(Equal([]string{"red"}))
+		_, err := os.Stat(filepath.Join(runDir, "blue"))
+		Expect(os.IsNotExist(err)).To(BeTrue())
+	})
+
+	It("creates a namespace distinct from the current one", func() {
+		netns, err := manager.Create("blue")
+		Expect(err).NotTo(HaveOccurred())
+		defer netns.Close()
+
+		Expect(getInode(netns.Path())).NotTo(Equal(getInode("/proc/self/ns/net")))
+
+		reopened, err := manager.Get("blue")
+		Expect(err).NotTo(HaveOccurred())
+		defer reopened.Close()
+		Expect(getInode(reopened.Path())).To(Equal(getInode(netns.Path())))
+
+		isPluginNS, cniErr := ns.CheckNetNS(netns.Path())
+		Expect(cniErr).To(BeNil())
+		Expect(isPluginNS).To(BeFalse())
+
+		err = netns.Do(func(ns.NetNS) error {
+			current, err := ns.GetCurrentNS()
+			if err != nil {
+				return err
+			}
+			defer current.Close()
+			if getInode(current.Path()) != getInode(netns.Path()) {
+				return errors.New("not running in the created namespace")
+			}
+			return nil
+		})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("refuses to create a namespace twice", func() {
+		netns, err := manager.Create("blue")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(netns.Close()).To(Succeed())
+
+		_, err = manager.Create("blue")
+		Expect(errors.Is(err, os.ErrExist)).To(BeTrue())
+	})
+
+	It("creates namespaces concurrently", func() {
+		const count = 8
+		var wg sync.WaitGroup
+		errs := make([]error, count)
+		for i := 0; i < count; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer GinkgoRecover()
+				defer wg.Done()
+				// every name is created twice, by different Managers
+				netns, err := ns.NewManager(runDir).Create(fmt.Sprintf("ns%d", i/2))
+				if err == nil {
+					err = netns.Close()
+				}
+				errs[i] = err
+			}(i)
+		}
+		wg.Wait()
+
+		failed := 0
+		for _, err := range errs {
+			if err != nil {
+				Expect(errors.Is(err, os.ErrExist)).To(BeTrue())
+				failed++
+			}
+		}
+		Expect(failed).To(Equal(count / 2))
+		Expect(manager.List()).To(Equal([]string{"ns0", "ns1", "ns2", "ns3"}))
+	})
+
+	It("returns typed errors for missing namespaces", func() {
+		_, err := manager.Get("missing")
+		var notExistErr ns.NSPathNotExistErr
+		Expect(errors.As(err, &notExistErr)).To(BeTrue())
+
+		Expect(errors.Is(manager.Remove("missing"), os.ErrNotExist)).To(BeTrue())
+	})
+
+	It("skips leftover files that are not namespaces", func() {
+		Expect(os.MkdirAll(runDir, 0o755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(runDir, "stale"), nil, 0o444)).To(Succeed())
+		Expect(manager.List()).To(BeEmpty())
+
+		// but they can still be removed
+		Expect(manager.Remove("stale")).To(Succeed())
+	})
+
+	It("rejects invalid names", func() {
+		for _, name := range []string{"", ".", "..", "a/b"} {
+			_, err := manager.Create(name)
+			Expect(err).To(MatchError(ContainSubstring("invalid network namespace name")))
+		}
+	})
+})
@@ -15,13 +15,281 @@
 package ns
 
 import (
+	"errors"
+	"fmt"
+	"os"
 	"runtime"
+	"strings"
+	"sync"
 
 	"github.com/vishvananda/netns"
+	"golang.org/x/sys/unix"
 
 	"github.com/containernetworking/cni/pkg/types"
 )
 
+// NetNS is a handle to a network namespace.
+type NetNS interface {
+	// Executes the passed closure in this object's network namespace,
+	// attempting to restore the original namespace before returning.
+	// However, since each OS thread can have a different network namespace,
+	// and Go's thread scheduling is highly variable, callers cannot
+	// guarantee any specific namespace is set unless operations that
+	// require that namespace are wrapped with Do().  Also, no code called
+	// from Do() should call runtime.UnlockOSThread(), or the risk
+	// of executing code in an incorrect namespace will be greater.  See
+	// https://github.com/golang/go/wiki/LockOSThread for further details.
+	Do(toRun func(NetNS) error) error
+
+	// Sets the current network namespace to this object's network namespace.
+	// Note that since Go's thread scheduling is highly variable, callers
+	// cannot guarantee the requested namespace will be the current namespace
+	// after this function is called; to ensure this wrap operations that
+	// require the namespace with Do() instead.
+	Set() error
+
+	// Returns the filesystem path representing this object's network namespace
+	Path() string
+
+	// Returns a file descriptor representing this object's network namespace
+	Fd() uintptr
+
+	// Cleans up this instance of the network namespace; if this instance
+	// is the last user the namespace will be destroyed
+	Close() error
+}
+
+type netNS struct {
+	file   *os.File
+	closed bool
+}
+
+// netNS implements the NetNS interface
+var _ NetNS = &netNS{}
+
+const (
+	// https://github.com/torvalds/linux/blob/master/include/uapi/linux/magic.h
+	NSFS_MAGIC   = unix.NSFS_MAGIC       //nolint:revive,stylecheck
+	PROCFS_MAGIC = unix.PROC_SUPER_MAGIC //nolint:revive,stylecheck
+
+	// nsGetNSType is the NS_GET_NSTYPE ioctl, _IO(0xb7, 0x3), from
+	// include/uapi/linux/nsfs.h
+	nsGetNSType = 0xb703
+)
+
+// NSPathNotExistErr is returned when a network namespace path does not
+// exist.
+type NSPathNotExistErr struct{ msg string }
+
+func (e NSPathNotExistErr) Error() string { return e.msg }
+
+// NSPathNotNSErr is returned when a path exists but is not a network
+// namespace, e.g. it is a regular file or another kind of namespace.
+type NSPathNotNSErr struct{ msg string }
+
+func (e NSPathNotNSErr) Error() string { return e.msg }
+
+// IsNSorErr returns nil if nspath is a network namespace, a
+// NSPathNotExistErr if it does not exist and a NSPathNotNSErr if it exists
+// but is not a network namespace.
+func IsNSorErr(nspath string) error {
+	stat := unix.Statfs_t{}
+	if err := unix.Statfs(nspath, &stat); err != nil {
+		if os.IsNotExist(err) {
+			err = NSPathNotExistErr{msg: fmt.Sprintf("failed to Statfs %q: %v", nspath, err)}
+		} else {
+			err = fmt.Errorf("failed to Statfs %q: %v", nspath, err)
+		}
+		return err
+	}
+
+	switch stat.Type {
+	case PROCFS_MAGIC, NSFS_MAGIC:
+	default:
+		return NSPathNotNSErr{msg: fmt.Sprintf("unknown FS magic on %q: %x", nspath, stat.Type)}
+	}
+
+	// Both magics also match other namespaces and plain /proc files, so
+	// ask the kernel what kind of namespace the path is
+	f, err := os.Open(nspath)
+	if err != nil {
+		return fmt.Errorf("failed to open %q: %v", nspath, err)
+	}
+	defer f.Close()
+	nsType, err := getNSType(int(f.Fd()))
+	if errors.Is(err, unix.ENOTTY) || errors.Is(err, unix.EINVAL) {
+		// Kernels before 4.11 do not know NS_GET_NSTYPE, and tell
+		// namespaces from plain files by the FS magic only
+		return checkNSFallback(nspath, int64(stat.Type)) //nolint:unconvert // Type is not int64 on all architectures
+	}
+	if err != nil {
+		return NSPathNotNSErr{msg: fmt.Sprintf("%q is not a namespace: %v", nspath, err)}
+	}
+	if nsType != unix.CLONE_NEWNET {
+		return NSPathNotNSErr{msg: fmt.Sprintf("%q is not a network namespace: type %#x", nspath, nsType)}
+	}
+	return nil
+}
+
+// getNSType returns the CLONE_NEW* type of the namespace open as fd
+var getNSType = func(fd int) (int, error) {
+	return unix.IoctlRetInt(fd, nsGetNSType)
+}
+
+// checkNSFallback checks nspath without NS_GET_NSTYPE. Files on nsfs are
+// namespaces, and namespaces on procfs, before Linux 3.19, are only
+// accepted as /proc/<pid>/ns/net links.
+func checkNSFallback(nspath string, fsType int64) error {
+	if fsType == NSFS_MAGIC {
+		return nil
+	}
+	if link, err := os.Readlink(nspath); err == nil && strings.HasPrefix(link, "net:") {
+		return nil
+	}
+	return NSPathNotNSErr{msg: fmt.Sprintf("%q is not a namespace", nspath)}
+}
+
+// GetCurrentNS returns an object representing the current OS thread's
+// network namespace
+func GetCurrentNS() (NetNS, error) {
+	// Lock the thread in case other goroutine executes in it and changes its
+	// network namespace after getCurrentThreadNetNSPath(), otherwise it might
+	// return an unexpected network namespace.
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	return GetNS(getCurrentThreadNetNSPath())
+}
+
+func getCurrentThreadNetNSPath() string {
+	// /proc/self/ns/net returns the namespace of the main thread, not
+	// of whatever thread this goroutine is running on.  Make sure we
+	// use the thread's net namespace since the thread is switching around
+	return fmt.Sprintf("/proc/%d/task/%d/ns/net", os.Getpid(), unix.Gettid())
+}
+
+// GetNS returns an object representing the network namespace at nspath.
+// It returns a NSPathNotExistErr or NSPathNotNSErr if nspath is not a
+// network namespace.
+func GetNS(nspath string) (NetNS, error) {
+	err := IsNSorErr(nspath)
+	if err != nil {
+		return nil, err
+	}
+
+	fd, err := os.Open(nspath)
+	if err != nil {
+		return nil, err
+	}
+
+	return &netNS{file: fd}, nil
+}
+
+func (ns *netNS) Path() string {
+	return ns.file.Name()
+}
+
+func (ns *netNS) Fd() uintptr {
+	return ns.file.Fd()
+}
+
+func (ns *netNS) errorIfClosed() error {
+	if ns.closed {
+		return fmt.Errorf("%q has already been closed", ns.file.Name())
+	}
+	return nil
+}
+
+func (ns *netNS) Close() error {
+	if err := ns.errorIfClosed(); err != nil {
+		return err
+	}
+
+	if err := ns.file.Close(); err != nil {
+		return fmt.Errorf("failed to close %q: %v", ns.file.Name(), err)
+	}
+	ns.closed = true
+
+	return nil
+}
+
+func (ns *netNS) Do(toRun func(NetNS) error) error {
+	if err := ns.errorIfClosed(); err != nil {
+		return err
+	}
+
+	containedCall := func(hostNS NetNS) error {
+		threadNS, err := GetCurrentNS()
+		if err != nil {
+			return fmt.Errorf("failed to open current netns: %v", err)
+		}
+		defer threadNS.Close()
+
+		// switch to target namespace
+		if err = ns.Set(); err != nil {
+			return fmt.Errorf("error switching to ns %v: %v", ns.file.Name(), err)
+		}
+		defer func() {
+			err := threadNS.Set() // switch back
+			if err == nil {
+				// Unlock the current thread only when we successfully switched back
+				// to the original namespace; otherwise leave the thread locked which
+				// will force the runtime to scrap the current thread, that is maybe
+				// not as optimal but at least always safe to do.
+				runtime.UnlockOSThread()
+			}
+		}()
+
+		return toRun(hostNS)
+	}
+
+	// save a handle to current network namespace
+	hostNS, err := GetCurrentNS()
+	if err != nil {
+		return fmt.Errorf("failed to open current namespace: %v", err)
+	}
+	defer hostNS.Close()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+
+	// Start the callback in a new green thread so that if we later fail
+	// to switch the namespace back to the original one, we can safely
+	// leave the thread locked to die without a risk of the current thread
+	// left lingering with incorrect namespace.
+	var innerError error
+	go func() {
+		defer wg.Done()
+		runtime.LockOSThread()
+		innerError = containedCall(hostNS)
+	}()
+	wg.Wait()
+
+	return innerError
+}
+
+func (ns *netNS) Set() error {
+	if err := ns.errorIfClosed(); err != nil {
+		return err
+	}
+
+	if err := unix.Setns(int(ns.Fd()), unix.CLONE_NEWNET); err != nil {
+		return fmt.Errorf("error switching to ns %v: %v", ns.file.Name(), err)
+	}
+
+	return nil
+}
+
+// WithNetNSPath executes the passed closure under the given network
+// namespace, restoring the original namespace afterwards.
+func WithNetNSPath(nspath string, toRun func(NetNS) error) error {
+	ns, err := GetNS(nspath)
+	if err != nil {
+		return err
+	}
+	defer ns.Close()
+	return ns.Do(toRun)
+}
+
 // Returns an object representing the current OS thread's network namespace
 func getCurrentNS() (netns.NsHandle, error) {
 	// Lock the thread in case other goroutine executes in it and changes its
@@ -32,7 +300,18 @@ func getCurrentNS() (netns.NsHandle, error) {
 	return netns.Get()
 }
 
+// CheckNetNS returns true if nsPath is the network namespace the plugin
+// runs in. A nsPath that does not exist, e.g. on DEL after the namespace
+// was deleted, is not an error; a nsPath that exists but is not a network
+// namespace is reported as ErrInvalidNetNS.
 func CheckNetNS(nsPath string) (bool, *types.Error) {
+	if err := IsNSorErr(nsPath); err != nil {
+		var notNSErr NSPathNotNSErr
+		if errors.As(err, &notNSErr) {
+			return false, types.NewError(types.ErrInvalidNetNS, "invalid netns from CNI_NETNS", err.Error())
+		}
+	}
+
 	ns, err := netns.GetFromPath(nsPath)
 	// Let plugins check whether nsPath from args is valid. Also support CNI DEL for empty nsPath as already-deleted nsPath.
 	if err != nil {
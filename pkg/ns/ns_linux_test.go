@@ -0,0 +1,155 @@
+// Copyright 2024 CNI authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ns_test
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"golang.org/x/sys/unix"
+
+	"github.com/containernetworking/cni/pkg/ns"
+	"github.com/containernetworking/cni/pkg/types"
+)
+
+func getInode(path string) uint64 {
+	var stat unix.Stat_t
+	Expect(unix.Stat(path, &stat)).To(Succeed())
+	return stat.Ino
+}
+
+var _ = Describe("Linux namespace operations", func() {
+	var tmpDir string
+
+	BeforeEach(func() {
+		tmpDir = GinkgoT().TempDir()
+	})
+
+	Describe("GetNS", func() {
+		It("returns a typed error when the path does not exist", func() {
+			_, err := ns.GetNS(filepath.Join(tmpDir, "missing"))
+			var notExistErr ns.NSPathNotExistErr
+			Expect(errors.As(err, &notExistErr)).To(BeTrue())
+		})
+
+		It("returns a typed error when the path is not a network namespace", func() {
+			path := filepath.Join(tmpDir, "file")
+			Expect(os.WriteFile(path, nil, 0o600)).To(Succeed())
+
+			_, err := ns.GetNS(path)
+			var notNSErr ns.NSPathNotNSErr
+			Expect(errors.As(err, &notNSErr)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("unknown FS magic"))
+		})
+
+		It("rejects namespaces of another kind and plain procfs files", func() {
+			for _, path := range []string{"/proc/self/ns/mnt", "/proc/self/ns/pid", "/proc/self/status"} {
+				_, err := ns.GetNS(path)
+				var notNSErr ns.NSPathNotNSErr
+				Expect(errors.As(err, &notNSErr)).To(BeTrue(), path)
+			}
+		})
+
+		It("opens the network namespace of a thread", func() {
+			netns, err := ns.GetNS("/proc/self/ns/net")
+			Expect(err).NotTo(HaveOccurred())
+			defer netns.Close()
+
+			Expect(netns.Path()).To(Equal("/proc/self/ns/net"))
+			Expect(netns.Fd()).NotTo(BeZero())
+		})
+	})
+
+	Describe("GetCurrentNS", func() {
+		It("returns the namespace of the current thread", func() {
+			netns, err := ns.GetCurrentNS()
+			Expect(err).NotTo(HaveOccurred())
+			defer netns.Close()
+
+			Expect(netns.Path()).To(HaveSuffix("/ns/net"))
+			Expect(getInode(netns.Path())).To(Equal(getInode("/proc/self/ns/net")))
+		})
+	})
+
+	Describe("Do", func() {
+		It("runs the closure in the namespace and passes the original one", func() {
+			netns, err := ns.GetCurrentNS()
+			Expect(err).NotTo(HaveOccurred())
+			defer netns.Close()
+
+			origInode := getInode(netns.Path())
+			err = netns.Do(func(hostNS ns.NetNS) error {
+				Expect(getInode(hostNS.Path())).To(Equal(origInode))
+				current, err := ns.GetCurrentNS()
+				if err != nil {
+					return err
+				}
+				defer current.Close()
+				if ino := getInode(current.Path()); ino != origInode {
+					return fmt.Errorf("running in namespace %d, expected %d", ino, origInode)
+				}
+				return nil
+			})
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("returns the closure's error", func() {
+			err := ns.WithNetNSPath("/proc/self/ns/net", func(ns.NetNS) error {
+				return errors.New("potato")
+			})
+			Expect(err).To(MatchError("potato"))
+		})
+	})
+
+	Describe("Close", func() {
+		It("refuses to use a closed namespace", func() {
+			netns, err := ns.GetCurrentNS()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(netns.Close()).To(Succeed())
+
+			Expect(netns.Close()).To(MatchError(HaveSuffix("has already been closed")))
+			Expect(netns.Set()).To(MatchError(HaveSuffix("has already been closed")))
+			Expect(netns.Do(func(ns.NetNS) error { return nil })).To(MatchError(HaveSuffix("has already been closed")))
+		})
+	})
+
+	Describe("CheckNetNS", func() {
+		It("detects the plugin's own namespace", func() {
+			isPluginNS, err := ns.CheckNetNS("/proc/self/ns/net")
+			Expect(err).To(BeNil())
+			Expect(isPluginNS).To(BeTrue())
+		})
+
+		It("accepts a namespace that does not exist", func() {
+			isPluginNS, err := ns.CheckNetNS(filepath.Join(tmpDir, "missing"))
+			Expect(err).To(BeNil())
+			Expect(isPluginNS).To(BeFalse())
+		})
+
+		It("rejects a path that is not a network namespace", func() {
+			path := filepath.Join(tmpDir, "file")
+			Expect(os.WriteFile(path, nil, 0o600)).To(Succeed())
+
+			isPluginNS, err := ns.CheckNetNS(path)
+			Expect(isPluginNS).To(BeFalse())
+			Expect(err).NotTo(BeNil())
+			Expect(err.Code).To(Equal(uint(types.ErrInvalidNetNS)))
+		})
+	})
+})
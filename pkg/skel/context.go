@@ -0,0 +1,126 @@
+// Copyright 2024 CNI authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package skel
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/containernetworking/cni/pkg/invoke"
+	"github.com/containernetworking/cni/pkg/types"
+	"github.com/containernetworking/cni/pkg/version"
+)
+
+// CNIFuncsContext is like CNIFuncs, but its callbacks are passed a context.
+// The context is cancelled when the plugin receives SIGTERM or SIGINT. For
+// ADD, CHECK and DEL, it also expires at the deadline the runtime passed in
+// CNI_DEADLINE, if any.
+type CNIFuncsContext struct {
+	Add    func(ctx context.Context, args *CmdArgs) error
+	Del    func(ctx context.Context, args *CmdArgs) error
+	Check  func(ctx context.Context, args *CmdArgs) error
+	GC     func(ctx context.Context, args *CmdArgs) error
+	Status func(ctx context.Context, args *CmdArgs) error
+}
+
+func ignoreContext(f func(*CmdArgs) error) func(context.Context, *CmdArgs) error {
+	if f == nil {
+		return nil
+	}
+	return func(_ context.Context, args *CmdArgs) error {
+		return f(args)
+	}
+}
+
+func (funcs CNIFuncs) withContext() CNIFuncsContext {
+	return CNIFuncsContext{
+		Add:    ignoreContext(funcs.Add),
+		Del:    ignoreContext(funcs.Del),
+		Check:  ignoreContext(funcs.Check),
+		GC:     ignoreContext(funcs.GC),
+		Status: ignoreContext(funcs.Status),
+	}
+}
+
+// withDeadline bounds ctx by the deadline in CNI_DEADLINE, if set
+func (t *dispatcher) withDeadline(ctx context.Context) (context.Context, context.CancelFunc, *types.Error) {
+	value := t.Getenv(invoke.DeadlineEnvVar)
+	if value == "" {
+		ctx, cancel := context.WithCancel(ctx)
+		return ctx, cancel, nil
+	}
+	deadline, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return nil, nil, types.NewError(types.ErrInvalidEnvironmentVariables,
+			fmt.Sprintf("invalid %s %q", invoke.DeadlineEnvVar, value), err.Error())
+	}
+	ctx, cancel := context.WithDeadline(ctx, deadline)
+	return ctx, cancel, nil
+}
+
+// PluginMainFuncsContextWithError is like PluginMainFuncsWithError, but
+// passes a context to the callbacks. See CNIFuncsContext.
+func PluginMainFuncsContextWithError(funcs CNIFuncsContext, versionInfo version.PluginInfo, about string) *types.Error {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	return (&dispatcher{
+		Getenv: os.Getenv,
+		Stdin:  os.Stdin,
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+	}).pluginMainContext(ctx, funcs, versionInfo, about)
+}
+
+// PluginIO is the environment, stdin, stdout and stderr a plugin runs with
+type PluginIO struct {
+	Getenv func(string) string
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// PluginMainIO is like PluginMainFuncsContextWithError, but takes the
+// environment and standard streams from pio instead of the process, and
+// passes ctx to the callbacks without listening for signals. It lets a
+// plugin be run in-process, e.g. by the skeltest package.
+func PluginMainIO(ctx context.Context, funcs CNIFuncsContext, versionInfo version.PluginInfo, about string, pio PluginIO) *types.Error {
+	return (&dispatcher{
+		Getenv: pio.Getenv,
+		Stdin:  pio.Stdin,
+		Stdout: pio.Stdout,
+		Stderr: pio.Stderr,
+	}).pluginMainContext(ctx, funcs, versionInfo, about)
+}
+
+// PluginMainFuncsContext is like PluginMainFuncs, but passes a context to
+// the callbacks. See CNIFuncsContext.
+//
+// When an error occurs in any func in CNIFuncsContext, PluginMainFuncsContext
+// will print the error as JSON to stdout and call os.Exit(1).
+func PluginMainFuncsContext(funcs CNIFuncsContext, versionInfo version.PluginInfo, about string) {
+	if e := PluginMainFuncsContextWithError(funcs, versionInfo, about); e != nil {
+		if err := e.Print(); err != nil {
+			log.Print("Error writing error JSON to stdout: ", err)
+		}
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,130 @@
+// Copyright 2024 CNI authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package skel
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/containernetworking/cni/pkg/types"
+	"github.com/containernetworking/cni/pkg/version"
+)
+
+var _ = Describe("dispatching to context-aware callbacks", func() {
+	var (
+		environment map[string]string
+		dispatch    *dispatcher
+		versionInfo version.PluginInfo
+		receivedCtx context.Context
+		received    *CmdArgs
+		funcs       CNIFuncsContext
+	)
+
+	BeforeEach(func() {
+		environment = map[string]string{
+			"CNI_COMMAND":     "ADD",
+			"CNI_CONTAINERID": "some-container-id",
+			"CNI_NETNS":       "/some/netns/path",
+			"CNI_IFNAME":      "eth0",
+			"CNI_PATH":        "/some/cni/path",
+		}
+		dispatch = &dispatcher{
+			Getenv: func(key string) string { return environment[key] },
+			Stdin:  strings.NewReader(`{ "name":"skel-test", "cniVersion": "1.0.0" }`),
+			Stdout: &bytes.Buffer{},
+			Stderr: &bytes.Buffer{},
+		}
+		versionInfo = version.PluginSupports("1.0.0")
+		receivedCtx, received = nil, nil
+		record := func(ctx context.Context, args *CmdArgs) error {
+			receivedCtx, received = ctx, args
+			return ctx.Err()
+		}
+		funcs = CNIFuncsContext{Add: record, Del: record, Check: record}
+	})
+
+	It("passes a context without a deadline by default", func() {
+		err := dispatch.pluginMainContext(context.Background(), funcs, versionInfo, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(received.ContainerID).To(Equal("some-container-id"))
+
+		_, ok := receivedCtx.Deadline()
+		Expect(ok).To(BeFalse())
+		// and cancels it once the callback returned
+		Expect(receivedCtx.Err()).To(Equal(context.Canceled))
+	})
+
+	It("bounds the context by CNI_DEADLINE", func() {
+		deadline := time.Now().Add(time.Hour).Truncate(time.Second)
+		environment["CNI_DEADLINE"] = deadline.Format(time.RFC3339)
+
+		err := dispatch.pluginMainContext(context.Background(), funcs, versionInfo, "")
+		Expect(err).NotTo(HaveOccurred())
+
+		ctxDeadline, ok := receivedCtx.Deadline()
+		Expect(ok).To(BeTrue())
+		Expect(ctxDeadline).To(BeTemporally("==", deadline))
+	})
+
+	It("returns the error of a callback whose deadline passed", func() {
+		environment["CNI_DEADLINE"] = time.Now().Add(-time.Second).Format(time.RFC3339Nano)
+
+		err := dispatch.pluginMainContext(context.Background(), funcs, versionInfo, "")
+		Expect(err).To(Equal(&types.Error{
+			Code: types.ErrInternal,
+			Msg:  context.DeadlineExceeded.Error(),
+		}))
+	})
+
+	It("passes on the cancellation of the parent context", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		err := dispatch.pluginMainContext(ctx, funcs, versionInfo, "")
+		Expect(err).To(HaveOccurred())
+		Expect(err.Msg).To(Equal(context.Canceled.Error()))
+	})
+
+	It("rejects an invalid CNI_DEADLINE", func() {
+		environment["CNI_DEADLINE"] = "tomorrow"
+
+		err := dispatch.pluginMainContext(context.Background(), funcs, versionInfo, "")
+		Expect(err).To(HaveOccurred())
+		Expect(err.Code).To(Equal(uint(types.ErrInvalidEnvironmentVariables)))
+		Expect(err.Msg).To(Equal(`invalid CNI_DEADLINE "tomorrow"`))
+		Expect(received).To(BeNil())
+	})
+
+	It("ignores CNI_DEADLINE for VERSION", func() {
+		environment["CNI_COMMAND"] = "VERSION"
+		environment["CNI_DEADLINE"] = "garbage"
+
+		err := dispatch.pluginMainContext(context.Background(), funcs, versionInfo, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(dispatch.Stdout.(*bytes.Buffer).String()).To(ContainSubstring(`"supportedVersions":["1.0.0"]`))
+	})
+
+	It("passes a context to plain CNIFuncs callbacks as well", func() {
+		called := false
+		plain := CNIFuncs{Add: func(*CmdArgs) error { called = true; return nil }}
+		Expect(dispatch.pluginMain(plain, versionInfo, "")).To(BeNil())
+		Expect(called).To(BeTrue())
+	})
+})
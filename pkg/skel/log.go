@@ -0,0 +1,252 @@
+// Copyright 2024 CNI authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package skel
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+)
+
+const (
+	// DefaultLogMaxSize is the size in megabytes at which log files are
+	// rotated if LogConfig.MaxSize is not set
+	DefaultLogMaxSize = 10
+	// DefaultLogMaxBackups is the number of rotated log files kept if
+	// LogConfig.MaxBackups is not set
+	DefaultLogMaxBackups = 1
+)
+
+// LogConfig configures the logger returned by CmdArgs.Logger. It is read
+// from the "log" key of the plugin's network configuration, e.g.
+//
+//	"log": {"file": "/var/log/cni/bridge.log", "level": "debug"}
+type LogConfig struct {
+	// File is the path records are appended to. If empty, records are
+	// written to stderr.
+	File string `json:"file,omitempty"`
+	// Level is the minimum level logged: "debug", "info" (the default),
+	// "warn" or "error"
+	Level string `json:"level,omitempty"`
+	// Format is "text" (the default) or "json"
+	Format string `json:"format,omitempty"`
+	// MaxSize is the size in megabytes File is rotated at. It defaults to
+	// DefaultLogMaxSize.
+	MaxSize int `json:"maxSize,omitempty"`
+	// MaxBackups is the number of rotated files kept, named File.1,
+	// File.2 and so on. It defaults to DefaultLogMaxBackups.
+	MaxBackups int `json:"maxBackups,omitempty"`
+}
+
+// argsLog is the logger of a callback, created on first use
+type argsLog struct {
+	once   sync.Once
+	logger *slog.Logger
+	file   *rotatingFile
+}
+
+// Logger returns a logger configured by the "log" key of the network
+// configuration, see LogConfig. Records are tagged with the plugin type,
+// command, container ID and interface name. The logger never writes to
+// stdout, where the plugin's result goes.
+//
+// The logger is only valid while the plugin callback that was passed
+// args runs; its file is closed when the callback returns. A CmdArgs that
+// was not passed to a callback by this package gets a logger writing to
+// stderr.
+func (args *CmdArgs) Logger() *slog.Logger {
+	if args.call == nil {
+		logger, _ := newLogger(args, "", os.Stderr)
+		return logger
+	}
+	c := args.call
+	c.log.once.Do(func() {
+		c.log.logger, c.log.file = newLogger(args, c.command, c.stderr)
+	})
+	return c.log.logger
+}
+
+func (l *argsLog) close() {
+	if l.file != nil {
+		l.file.Close()
+	}
+}
+
+// newLogger builds the logger for args. If the configuration is invalid
+// or the log file cannot be opened, the logger writes to stderr and its
+// first record says why.
+func newLogger(args *CmdArgs, command string, stderr io.Writer) (*slog.Logger, *rotatingFile) {
+	var conf struct {
+		Type string    `json:"type"`
+		Log  LogConfig `json:"log"`
+	}
+	confErr := json.Unmarshal(args.StdinData, &conf)
+
+	opts := &slog.HandlerOptions{}
+	if conf.Log.Level != "" {
+		var level slog.Level
+		if err := level.UnmarshalText([]byte(conf.Log.Level)); err != nil && confErr == nil {
+			confErr = fmt.Errorf("invalid log level %q", conf.Log.Level)
+		}
+		opts.Level = level
+	}
+
+	var out io.Writer = stderr
+	var file *rotatingFile
+	if conf.Log.File != "" && confErr == nil {
+		f, err := openRotatingFile(conf.Log.File, conf.Log.MaxSize, conf.Log.MaxBackups)
+		if err != nil {
+			confErr = err
+		} else {
+			out, file = f, f
+		}
+	}
+
+	var handler slog.Handler
+	switch strings.ToLower(conf.Log.Format) {
+	case "", "text":
+		handler = slog.NewTextHandler(out, opts)
+	case "json":
+		handler = slog.NewJSONHandler(out, opts)
+	default:
+		handler = slog.NewTextHandler(out, opts)
+		if confErr == nil {
+			confErr = fmt.Errorf("invalid log format %q", conf.Log.Format)
+		}
+	}
+
+	var attrs []any
+	if conf.Type != "" {
+		attrs = append(attrs, "plugin", conf.Type)
+	}
+	if command != "" {
+		attrs = append(attrs, "command", command)
+	}
+	if args.ContainerID != "" {
+		attrs = append(attrs, "containerID", args.ContainerID)
+	}
+	if args.IfName != "" {
+		attrs = append(attrs, "ifname", args.IfName)
+	}
+	logger := slog.New(handler).With(attrs...)
+
+	if confErr != nil {
+		logger.Warn("invalid log configuration, logging to stderr", "error", confErr)
+	}
+	return logger, file
+}
+
+// rotatingFile is an append-only file that is rotated before a write
+// would grow it beyond maxSize. The size is taken from the file itself,
+// so several plugin processes can share it.
+type rotatingFile struct {
+	path       string
+	maxSize    int64
+	maxBackups int
+
+	mu sync.Mutex
+	f  *os.File
+}
+
+func openRotatingFile(path string, maxSizeMB, maxBackups int) (*rotatingFile, error) {
+	if maxSizeMB <= 0 {
+		maxSizeMB = DefaultLogMaxSize
+	}
+	if maxBackups <= 0 {
+		maxBackups = DefaultLogMaxBackups
+	}
+	r := &rotatingFile{
+		path:       path,
+		maxSize:    int64(maxSizeMB) * 1024 * 1024,
+		maxBackups: maxBackups,
+	}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *rotatingFile) open() error {
+	f, err := os.OpenFile(r.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	r.f = f
+	return nil
+}
+
+func (r *rotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.f == nil {
+		return 0, os.ErrClosed
+	}
+	if err := r.reopenIfRotated(); err != nil {
+		return 0, err
+	}
+	if info, err := r.f.Stat(); err == nil && info.Size() > 0 && info.Size()+int64(len(p)) > r.maxSize {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	return r.f.Write(p)
+}
+
+// reopenIfRotated reopens path if another process rotated it since it was
+// opened
+func (r *rotatingFile) reopenIfRotated() error {
+	opened, err := r.f.Stat()
+	if err != nil {
+		return nil
+	}
+	current, err := os.Stat(r.path)
+	if err == nil && os.SameFile(opened, current) {
+		return nil
+	}
+	r.f.Close()
+	r.f = nil
+	return r.open()
+}
+
+// rotate shifts path.N to path.N+1, dropping the oldest, moves the current
+// file to path.1 and reopens path
+func (r *rotatingFile) rotate() error {
+	r.f.Close()
+	r.f = nil
+	for i := r.maxBackups - 1; i >= 1; i-- {
+		_ = os.Rename(fmt.Sprintf("%s.%d", r.path, i), fmt.Sprintf("%s.%d", r.path, i+1))
+	}
+	if err := os.Rename(r.path, r.path+".1"); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to rotate log file: %w", err)
+	}
+	return r.open()
+}
+
+func (r *rotatingFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.f == nil {
+		return nil
+	}
+	err := r.f.Close()
+	r.f = nil
+	return err
+}
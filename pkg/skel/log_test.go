@@ -0,0 +1,163 @@
+// Copyright 2024 CNI authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package skel
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/containernetworking/cni/pkg/version"
+)
+
+var _ = Describe("the plugin logger", func() {
+	var (
+		environment map[string]string
+		stdout      *bytes.Buffer
+		stderr      *bytes.Buffer
+		logFile     string
+	)
+
+	BeforeEach(func() {
+		environment = map[string]string{
+			"CNI_COMMAND":     "ADD",
+			"CNI_CONTAINERID": "some-container-id",
+			"CNI_NETNS":       "/some/netns/path",
+			"CNI_IFNAME":      "eth0",
+			"CNI_PATH":        "/some/cni/path",
+		}
+		stdout = &bytes.Buffer{}
+		stderr = &bytes.Buffer{}
+		logFile = filepath.Join(GinkgoT().TempDir(), "plugin.log")
+	})
+
+	run := func(conf string, add func(*CmdArgs) error) {
+		dispatch := &dispatcher{
+			Getenv: func(key string) string { return environment[key] },
+			Stdin:  strings.NewReader(conf),
+			Stdout: stdout,
+			Stderr: stderr,
+		}
+		err := dispatch.pluginMain(CNIFuncs{Add: add}, version.PluginSupports("1.0.0"), "")
+		Expect(err).NotTo(HaveOccurred())
+	}
+
+	It("writes tagged records to the configured file", func() {
+		run(fmt.Sprintf(`{ "name": "skel-test", "type": "some-plugin", "cniVersion": "1.0.0", "log": { "file": %q, "format": "json" } }`, logFile),
+			func(args *CmdArgs) error {
+				args.Logger().Info("hello", "key", "value")
+				return nil
+			})
+
+		data, err := os.ReadFile(logFile)
+		Expect(err).NotTo(HaveOccurred())
+		var record map[string]interface{}
+		Expect(json.Unmarshal(data, &record)).To(Succeed())
+		Expect(record).To(HaveKeyWithValue("msg", "hello"))
+		Expect(record).To(HaveKeyWithValue("key", "value"))
+		Expect(record).To(HaveKeyWithValue("plugin", "some-plugin"))
+		Expect(record).To(HaveKeyWithValue("command", "ADD"))
+		Expect(record).To(HaveKeyWithValue("containerID", "some-container-id"))
+		Expect(record).To(HaveKeyWithValue("ifname", "eth0"))
+
+		Expect(stdout.String()).To(BeEmpty())
+		Expect(stderr.String()).To(BeEmpty())
+	})
+
+	It("logs to stderr without a file, never to stdout", func() {
+		run(`{ "name": "skel-test", "cniVersion": "1.0.0" }`, func(args *CmdArgs) error {
+			args.Logger().Info("hello")
+			return nil
+		})
+
+		Expect(stdout.String()).To(BeEmpty())
+		Expect(stderr.String()).To(ContainSubstring("msg=hello"))
+		Expect(stderr.String()).To(ContainSubstring("command=ADD"))
+	})
+
+	It("honors the configured level", func() {
+		run(`{ "name": "skel-test", "cniVersion": "1.0.0", "log": { "level": "warn" } }`, func(args *CmdArgs) error {
+			args.Logger().Info("quiet")
+			args.Logger().Warn("loud")
+			return nil
+		})
+
+		Expect(stderr.String()).NotTo(ContainSubstring("quiet"))
+		Expect(stderr.String()).To(ContainSubstring("loud"))
+	})
+
+	It("falls back to stderr with a warning on an invalid configuration", func() {
+		run(`{ "name": "skel-test", "cniVersion": "1.0.0", "log": { "format": "xml" } }`, func(args *CmdArgs) error {
+			args.Logger().Info("hello")
+			return nil
+		})
+
+		Expect(stderr.String()).To(ContainSubstring(`invalid log format \"xml\"`))
+		Expect(stderr.String()).To(ContainSubstring("msg=hello"))
+	})
+
+	It("detaches the logging state when the callback returns", func() {
+		var received *CmdArgs
+		run(fmt.Sprintf(`{ "name": "skel-test", "cniVersion": "1.0.0", "log": { "file": %q } }`, logFile),
+			func(args *CmdArgs) error {
+				received = args
+				args.Logger().Info("hello")
+				return nil
+			})
+
+		Expect(received.call).To(BeNil())
+	})
+
+	Describe("rotation", func() {
+		It("rotates the file before it grows beyond its maximum size", func() {
+			f, err := openRotatingFile(logFile, 1, 2)
+			Expect(err).NotTo(HaveOccurred())
+			defer f.Close()
+
+			chunk := bytes.Repeat([]byte("x"), 600*1024)
+			for i := 0; i < 4; i++ {
+				_, err := f.Write(chunk)
+				Expect(err).NotTo(HaveOccurred())
+			}
+
+			for _, path := range []string{logFile, logFile + ".1", logFile + ".2"} {
+				info, err := os.Stat(path)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(info.Size()).To(BeEquivalentTo(len(chunk)))
+			}
+			Expect(logFile + ".3").NotTo(BeAnExistingFile())
+		})
+
+		It("follows a rotation by another process", func() {
+			f, err := openRotatingFile(logFile, 1, 1)
+			Expect(err).NotTo(HaveOccurred())
+			defer f.Close()
+
+			Expect(os.Rename(logFile, logFile+".1")).To(Succeed())
+			_, err = f.Write([]byte("after\n"))
+			Expect(err).NotTo(HaveOccurred())
+
+			data, err := os.ReadFile(logFile)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(data)).To(Equal("after\n"))
+		})
+	})
+})
@@ -0,0 +1,66 @@
+// Copyright 2024 CNI authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package skel
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"runtime/debug"
+	"time"
+
+	"github.com/containernetworking/cni/pkg/types"
+)
+
+// PanicLogEnvVar names an environment variable holding the path of a file
+// that the stack traces of panicking plugin callbacks are appended to.
+const PanicLogEnvVar = "CNI_PANIC_LOG"
+
+// callRecovering calls toCall, turning a panic into an ErrInternal error
+// with the stack trace in its details. The stack trace is also appended to
+// the file named by CNI_PANIC_LOG, if set.
+func (t *dispatcher) callRecovering(ctx context.Context, toCall func(context.Context, *CmdArgs) error, cmdArgs *CmdArgs) (err error) {
+	defer func() {
+		r := recover()
+		if r == nil {
+			return
+		}
+		stack := debug.Stack()
+		msg := fmt.Sprintf("plugin panicked: %v", r)
+		if logErr := t.logPanic(msg, stack, cmdArgs); logErr != nil {
+			_, _ = fmt.Fprintf(t.Stderr, "failed to write panic log: %v\n", logErr)
+		}
+		err = types.NewError(types.ErrInternal, msg, string(stack))
+	}()
+	return toCall(ctx, cmdArgs)
+}
+
+func (t *dispatcher) logPanic(msg string, stack []byte, cmdArgs *CmdArgs) error {
+	path := t.Getenv(PanicLogEnvVar)
+	if path == "" {
+		return nil
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(f, "%s %s command=%s containerID=%s ifname=%s\n%s\n",
+		time.Now().UTC().Format(time.RFC3339Nano), msg,
+		t.Getenv("CNI_COMMAND"), cmdArgs.ContainerID, cmdArgs.IfName, stack)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -0,0 +1,100 @@
+// Copyright 2024 CNI authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package skel
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/containernetworking/cni/pkg/types"
+	"github.com/containernetworking/cni/pkg/version"
+)
+
+var _ = Describe("recovering from panicking callbacks", func() {
+	var (
+		environment map[string]string
+		stderr      *bytes.Buffer
+		dispatch    *dispatcher
+		funcs       CNIFuncs
+	)
+
+	BeforeEach(func() {
+		environment = map[string]string{
+			"CNI_COMMAND":     "ADD",
+			"CNI_CONTAINERID": "some-container-id",
+			"CNI_NETNS":       "/some/netns/path",
+			"CNI_IFNAME":      "eth0",
+			"CNI_PATH":        "/some/cni/path",
+		}
+		stderr = &bytes.Buffer{}
+		dispatch = &dispatcher{
+			Getenv: func(key string) string { return environment[key] },
+			Stdin:  strings.NewReader(`{ "name":"skel-test", "cniVersion": "1.0.0" }`),
+			Stdout: &bytes.Buffer{},
+			Stderr: stderr,
+		}
+		funcs = CNIFuncs{
+			Add: func(*CmdArgs) error { panic("potato") },
+		}
+	})
+
+	It("returns an internal error with the stack trace", func() {
+		err := dispatch.pluginMain(funcs, version.PluginSupports("1.0.0"), "")
+		Expect(err).NotTo(BeNil())
+		Expect(err.Code).To(Equal(uint(types.ErrInternal)))
+		Expect(err.Msg).To(Equal("plugin panicked: potato"))
+		Expect(err.Details).To(ContainSubstring("goroutine"))
+		Expect(err.Details).To(ContainSubstring("panic_test.go"))
+	})
+
+	It("recovers panics with error values", func() {
+		funcs.Add = func(*CmdArgs) error {
+			var m map[string]int
+			m["boom"] = 1
+			return nil
+		}
+		err := dispatch.pluginMain(funcs, version.PluginSupports("1.0.0"), "")
+		Expect(err).NotTo(BeNil())
+		Expect(err.Code).To(Equal(uint(types.ErrInternal)))
+		Expect(err.Msg).To(Equal("plugin panicked: assignment to entry in nil map"))
+	})
+
+	It("appends the stack trace to the panic log", func() {
+		logPath := filepath.Join(GinkgoT().TempDir(), "panic.log")
+		environment["CNI_PANIC_LOG"] = logPath
+
+		Expect(dispatch.pluginMain(funcs, version.PluginSupports("1.0.0"), "")).NotTo(BeNil())
+
+		contents, err := os.ReadFile(logPath)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(contents)).To(ContainSubstring("plugin panicked: potato command=ADD containerID=some-container-id ifname=eth0\n"))
+		Expect(string(contents)).To(ContainSubstring("panic_test.go"))
+		Expect(stderr.String()).To(BeEmpty())
+	})
+
+	It("still returns the error when the panic log cannot be written", func() {
+		environment["CNI_PANIC_LOG"] = filepath.Join(GinkgoT().TempDir(), "missing", "panic.log")
+
+		err := dispatch.pluginMain(funcs, version.PluginSupports("1.0.0"), "")
+		Expect(err).NotTo(BeNil())
+		Expect(err.Msg).To(Equal("plugin panicked: potato"))
+		Expect(stderr.String()).To(HavePrefix("failed to write panic log:"))
+	})
+})
@@ -18,6 +18,7 @@ package skel
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -42,6 +43,46 @@ type CmdArgs struct {
 	Path          string
 	NetnsOverride string
 	StdinData     []byte
+
+	// call is set while the args are passed to a plugin callback
+	call *callState
+}
+
+// callState holds what a plugin callback writes to
+type callState struct {
+	command string
+	stdout  io.Writer
+	stderr  io.Writer
+	log     argsLog
+}
+
+// Stdout returns the writer the plugin's result must be printed to. It is
+// os.Stdout unless the plugin is run in-process, e.g. by the skeltest
+// package, so plugins should print their result with PrintResult rather
+// than types.PrintResult.
+func (args *CmdArgs) Stdout() io.Writer {
+	if args.call == nil {
+		return os.Stdout
+	}
+	return args.call.stdout
+}
+
+// PrintResult is like types.PrintResult, but prints to args.Stdout().
+func (args *CmdArgs) PrintResult(result types.Result, version string) error {
+	newResult, err := result.GetAsVersion(version)
+	if err != nil {
+		return err
+	}
+	return newResult.PrintTo(args.Stdout())
+}
+
+// endCall closes the logger of the callback args were passed to and
+// detaches the callback's state from args
+func (args *CmdArgs) endCall() {
+	if args.call != nil {
+		args.call.log.close()
+	}
+	args.call = nil
 }
 
 type dispatcher struct {
@@ -197,7 +238,7 @@ func (t *dispatcher) getCmdArgsFromEnv() (string, *CmdArgs, *types.Error) {
 	return cmd, cmdArgs, nil
 }
 
-func (t *dispatcher) checkVersionAndCall(cmdArgs *CmdArgs, pluginVersionInfo version.PluginInfo, toCall/*检查通过后，要执行的回调*/ func(*CmdArgs) error) *types.Error {
+func (t *dispatcher) checkVersionAndCall(ctx context.Context, cmdArgs *CmdArgs, pluginVersionInfo version.PluginInfo, toCall/*检查通过后，要执行的回调*/ func(context.Context, *CmdArgs) error) *types.Error {
 	/*取cni版本号*/
 	configVersion, err := t.ConfVersionDecoder.Decode(cmdArgs.StdinData)
 	if err != nil {
@@ -214,8 +255,11 @@ func (t *dispatcher) checkVersionAndCall(cmdArgs *CmdArgs, pluginVersionInfo ver
 		return nil
 	}
 
+	cmdArgs.call = &callState{command: t.Getenv("CNI_COMMAND"), stdout: t.Stdout, stderr: t.Stderr}
+	defer cmdArgs.endCall()
+
 	/*触发toCall回调*/
-	if err = toCall(cmdArgs); err != nil {
+	if err = t.callRecovering(ctx, toCall, cmdArgs); err != nil {
 		var e *types.Error
 		if errors.As(err, &e) {
 			// don't wrap Error in Error
@@ -254,6 +298,10 @@ func validateConfig(jsonBytes []byte) *types.Error {
 	cmd=del时，cmdDel被调用
 自环境变量中提取cmd,cmdArgs*/
 func (t *dispatcher) pluginMain(funcs CNIFuncs, versionInfo version.PluginInfo, about string) *types.Error {
+	return t.pluginMainContext(context.Background(), funcs.withContext(), versionInfo, about)
+}
+
+func (t *dispatcher) pluginMainContext(ctx context.Context, funcs CNIFuncsContext, versionInfo version.PluginInfo, about string) *types.Error {
 	cmd, cmdArgs, err := t.getCmdArgsFromEnv()
 	if err != nil {
 		// Print the about string to stderr when no command is set
@@ -265,10 +313,21 @@ func (t *dispatcher) pluginMain(funcs CNIFuncs, versionInfo version.PluginInfo,
 		return err
 	}
 
+	// Only commands on a container are bounded by CNI_DEADLINE, so that a
+	// malformed deadline does not keep runtimes from asking for VERSION
+	if cmd == "ADD" || cmd == "CHECK" || cmd == "DEL" {
+		var cancel context.CancelFunc
+		ctx, cancel, err = t.withDeadline(ctx)
+		if err != nil {
+			return err
+		}
+		defer cancel()
+	}
+
 	switch cmd {
 	case "ADD":
 		/*检查版本后，触发cmdAdd回调*/
-		err = t.checkVersionAndCall(cmdArgs, versionInfo, funcs.Add)
+		err = t.checkVersionAndCall(ctx, cmdArgs, versionInfo, funcs.Add)
 		if err != nil {
 			return err
 		}
@@ -301,7 +360,7 @@ func (t *dispatcher) pluginMain(funcs CNIFuncs, versionInfo version.PluginInfo,
 				return types.NewError(types.ErrDecodingFailure, err.Error(), "")
 			} else if gtet {
 				/*触发cmdCheck调用*/
-				if err := t.checkVersionAndCall(cmdArgs, versionInfo, funcs.Check); err != nil {
+				if err := t.checkVersionAndCall(ctx, cmdArgs, versionInfo, funcs.Check); err != nil {
 					return err
 				}
 				return nil
@@ -310,7 +369,7 @@ func (t *dispatcher) pluginMain(funcs CNIFuncs, versionInfo version.PluginInfo,
 		return types.NewError(types.ErrIncompatibleCNIVersion, "plugin version does not allow CHECK", "")
 	case "DEL":
 		/*触发cmdDel回调*/
-		err = t.checkVersionAndCall(cmdArgs, versionInfo, funcs.Del)
+		err = t.checkVersionAndCall(ctx, cmdArgs, versionInfo, funcs.Del)
 		if err != nil {
 			return err
 		}
@@ -337,7 +396,7 @@ func (t *dispatcher) pluginMain(funcs CNIFuncs, versionInfo version.PluginInfo,
 			if err != nil {
 				return types.NewError(types.ErrDecodingFailure, err.Error(), "")
 			} else if gtet {
-				if err := t.checkVersionAndCall(cmdArgs, versionInfo, funcs.GC); err != nil {
+				if err := t.checkVersionAndCall(ctx, cmdArgs, versionInfo, funcs.GC); err != nil {
 					return err
 				}
 				return nil
@@ -359,7 +418,7 @@ func (t *dispatcher) pluginMain(funcs CNIFuncs, versionInfo version.PluginInfo,
 			if err != nil {
 				return types.NewError(types.ErrDecodingFailure, err.Error(), "")
 			} else if gtet {
-				if err := t.checkVersionAndCall(cmdArgs, versionInfo, funcs.Status); err != nil {
+				if err := t.checkVersionAndCall(ctx, cmdArgs, versionInfo, funcs.Status); err != nil {
 					return err
 				}
 				return nil
@@ -417,6 +476,9 @@ type CNIFuncs struct {
 // For a plugin to comply with the CNI spec, it must print any error to stdout
 // as JSON and then exit with nonzero status code.
 //
+// A panic in a callback is recovered and returned as an ErrInternal error
+// with the stack trace in its details; see PanicLogEnvVar.
+//
 // To let this package automatically handle errors and call os.Exit(1) for you,
 // use PluginMainFuncs() instead.
 func PluginMainFuncsWithError(funcs CNIFuncs, versionInfo version.PluginInfo, about string) *types.Error {
@@ -0,0 +1,245 @@
+// Copyright 2024 CNI authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package skeltest runs the callbacks of a plugin built with the skel
+// package in-process, through the same argument parsing, version checks and
+// error handling as the plugin binary, so that plugins can be tested end to
+// end without building and executing them.
+//
+// Plugins must print their results with CmdArgs.PrintResult, or to
+// CmdArgs.Stdout(), to be tested this way: types.PrintResult and
+// Result.Print write to the process's os.Stdout, which skeltest does not
+// capture.
+package skeltest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/containernetworking/cni/pkg/skel"
+	"github.com/containernetworking/cni/pkg/types"
+	"github.com/containernetworking/cni/pkg/types/create"
+	"github.com/containernetworking/cni/pkg/version"
+)
+
+// Default values used for Args fields that are left empty
+const (
+	DefaultContainerID = "skeltest-container"
+	DefaultNetns       = "/var/run/netns/skeltest"
+	DefaultIfName      = "eth0"
+	DefaultPath        = "/opt/cni/bin"
+)
+
+// Plugin is a plugin under test
+type Plugin struct {
+	Funcs       skel.CNIFuncsContext
+	VersionInfo version.PluginInfo
+	About       string
+}
+
+// New returns a Plugin running funcs
+func New(funcs skel.CNIFuncs, versionInfo version.PluginInfo) *Plugin {
+	return NewContext(skel.CNIFuncsContext{
+		Add:    withoutContext(funcs.Add),
+		Del:    withoutContext(funcs.Del),
+		Check:  withoutContext(funcs.Check),
+		GC:     withoutContext(funcs.GC),
+		Status: withoutContext(funcs.Status),
+	}, versionInfo)
+}
+
+// NewContext returns a Plugin running funcs, which are passed the context
+// given to the Plugin's methods
+func NewContext(funcs skel.CNIFuncsContext, versionInfo version.PluginInfo) *Plugin {
+	return &Plugin{Funcs: funcs, VersionInfo: versionInfo}
+}
+
+func withoutContext(f func(*skel.CmdArgs) error) func(context.Context, *skel.CmdArgs) error {
+	if f == nil {
+		return nil
+	}
+	return func(_ context.Context, args *skel.CmdArgs) error {
+		return f(args)
+	}
+}
+
+// Args are the parameters a plugin is invoked with. Empty fields are set to
+// the Default* values, except for GC, STATUS and VERSION, which do not take
+// a container.
+type Args struct {
+	ContainerID string
+	Netns       string
+	IfName      string
+	// CNIArgs is passed in CNI_ARGS, see FormatCNIArgs
+	CNIArgs string
+	Path    string
+	// Env holds further environment variables, e.g. CNI_NETNS_OVERRIDE or
+	// CNI_DEADLINE. They take precedence over the fields above; setting
+	// a variable to "" makes it missing.
+	Env map[string]string
+}
+
+func (args Args) environment(command string) map[string]string {
+	env := map[string]string{
+		"CNI_COMMAND": command,
+		"CNI_ARGS":    args.CNIArgs,
+		"CNI_PATH":    valueOr(args.Path, DefaultPath),
+	}
+	switch command {
+	case "GC", "STATUS", "VERSION":
+		env["CNI_CONTAINERID"] = args.ContainerID
+		env["CNI_NETNS"] = args.Netns
+		env["CNI_IFNAME"] = args.IfName
+	default:
+		env["CNI_CONTAINERID"] = valueOr(args.ContainerID, DefaultContainerID)
+		env["CNI_NETNS"] = valueOr(args.Netns, DefaultNetns)
+		env["CNI_IFNAME"] = valueOr(args.IfName, DefaultIfName)
+	}
+	for k, v := range args.Env {
+		env[k] = v
+	}
+	return env
+}
+
+func valueOr(value, def string) string {
+	if value == "" {
+		return def
+	}
+	return value
+}
+
+// Output is what a plugin invocation produced
+type Output struct {
+	Stdout []byte
+	Stderr []byte
+	// Err is the error the plugin would print and exit with, or nil
+	Err *types.Error
+}
+
+// Run invokes the plugin with the given CNI_COMMAND, arguments and network
+// configuration and returns its output.
+func (p *Plugin) Run(ctx context.Context, command string, args Args, conf []byte) *Output {
+	env := args.environment(command)
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	err := skel.PluginMainIO(ctx, p.Funcs, p.VersionInfo, p.About, skel.PluginIO{
+		Getenv: func(key string) string { return env[key] },
+		Stdin:  bytes.NewReader(conf),
+		Stdout: stdout,
+		Stderr: stderr,
+	})
+	return &Output{
+		Stdout: stdout.Bytes(),
+		Stderr: stderr.Bytes(),
+		Err:    err,
+	}
+}
+
+// Add runs ADD and returns the result the plugin printed. It fails if the
+// plugin succeeded without printing a result to args.Stdout(), e.g. because
+// it used types.PrintResult instead of args.PrintResult.
+func (p *Plugin) Add(ctx context.Context, args Args, conf []byte) (types.Result, *types.Error) {
+	out := p.Run(ctx, "ADD", args, conf)
+	if out.Err != nil {
+		return nil, out.Err
+	}
+	if len(bytes.TrimSpace(out.Stdout)) == 0 {
+		return nil, types.NewError(types.ErrDecodingFailure, "plugin printed no result",
+			"results must be printed with args.PrintResult or to args.Stdout(); types.PrintResult and Result.Print write to os.Stdout, which is not captured")
+	}
+	result, err := create.CreateFromBytes(out.Stdout)
+	if err != nil {
+		return nil, types.NewError(types.ErrDecodingFailure, fmt.Sprintf("failed to decode result: %v", err), string(out.Stdout))
+	}
+	return result, nil
+}
+
+// Check runs CHECK
+func (p *Plugin) Check(ctx context.Context, args Args, conf []byte) *types.Error {
+	return p.Run(ctx, "CHECK", args, conf).Err
+}
+
+// Del runs DEL
+func (p *Plugin) Del(ctx context.Context, args Args, conf []byte) *types.Error {
+	return p.Run(ctx, "DEL", args, conf).Err
+}
+
+// GC runs GC
+func (p *Plugin) GC(ctx context.Context, args Args, conf []byte) *types.Error {
+	return p.Run(ctx, "GC", args, conf).Err
+}
+
+// Status runs STATUS
+func (p *Plugin) Status(ctx context.Context, args Args, conf []byte) *types.Error {
+	return p.Run(ctx, "STATUS", args, conf).Err
+}
+
+// Version runs VERSION and returns the versions the plugin reported
+func (p *Plugin) Version(ctx context.Context) (version.PluginInfo, *types.Error) {
+	out := p.Run(ctx, "VERSION", Args{}, []byte(`{}`))
+	if out.Err != nil {
+		return nil, out.Err
+	}
+	info, err := (&version.PluginDecoder{}).Decode(out.Stdout)
+	if err != nil {
+		return nil, types.NewError(types.ErrDecodingFailure, err.Error(), string(out.Stdout))
+	}
+	return info, nil
+}
+
+// WithPrevResult returns conf with prevResult set to result, converted to
+// the cniVersion of conf, like a runtime does for chained plugins.
+func WithPrevResult(conf []byte, result types.Result) ([]byte, error) {
+	var raw map[string]interface{}
+	if err := json.Unmarshal(conf, &raw); err != nil {
+		return nil, fmt.Errorf("failed to decode network configuration: %w", err)
+	}
+	confVersion, err := create.DecodeVersion(conf)
+	if err != nil {
+		return nil, err
+	}
+	converted, err := result.GetAsVersion(confVersion)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert prevResult to version %s: %w", confVersion, err)
+	}
+	resultBytes, err := json.Marshal(converted)
+	if err != nil {
+		return nil, err
+	}
+	var prevResult map[string]interface{}
+	if err := json.Unmarshal(resultBytes, &prevResult); err != nil {
+		return nil, err
+	}
+	raw["prevResult"] = prevResult
+	return json.Marshal(raw)
+}
+
+// FormatCNIArgs formats args for CNI_ARGS as semicolon-separated KEY=VALUE
+// pairs, sorted by key
+func FormatCNIArgs(args map[string]string) string {
+	keys := make([]string, 0, len(args))
+	for k := range args {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, len(keys))
+	for i, k := range keys {
+		pairs[i] = k + "=" + args[k]
+	}
+	return strings.Join(pairs, ";")
+}
@@ -0,0 +1,194 @@
+// Copyright 2024 CNI authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package skeltest_test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/containernetworking/cni/pkg/skel"
+	"github.com/containernetworking/cni/pkg/skel/skeltest"
+	"github.com/containernetworking/cni/pkg/types"
+	current "github.com/containernetworking/cni/pkg/types/100"
+	"github.com/containernetworking/cni/pkg/version"
+)
+
+const conf = `{ "name": "test", "type": "fake", "cniVersion": "1.0.0" }`
+
+var _ = Describe("running a plugin in-process", func() {
+	var (
+		received *skel.CmdArgs
+		plugin   *skeltest.Plugin
+		addErr   error
+	)
+
+	BeforeEach(func() {
+		received = nil
+		addErr = nil
+		plugin = skeltest.New(skel.CNIFuncs{
+			Add: func(args *skel.CmdArgs) error {
+				received = args
+				if addErr != nil {
+					return addErr
+				}
+				ipnet, _ := types.ParseCIDR("10.0.0.2/24")
+				return args.PrintResult(&current.Result{
+					CNIVersion: current.ImplementedSpecVersion,
+					IPs:        []*current.IPConfig{{Address: *ipnet}},
+				}, "1.0.0")
+			},
+			Del: func(args *skel.CmdArgs) error {
+				received = args
+				return nil
+			},
+		}, version.PluginSupports("0.4.0", "1.0.0"))
+	})
+
+	It("passes the arguments and returns the result of ADD", func() {
+		result, err := plugin.Add(context.TODO(), skeltest.Args{
+			IfName:  "net1",
+			CNIArgs: skeltest.FormatCNIArgs(map[string]string{"K8S_POD_NAME": "pod", "IgnoreUnknown": "true"}),
+		}, []byte(conf))
+		Expect(err).To(BeNil())
+
+		Expect(received.ContainerID).To(Equal(skeltest.DefaultContainerID))
+		Expect(received.Netns).To(Equal(skeltest.DefaultNetns))
+		Expect(received.IfName).To(Equal("net1"))
+		Expect(received.Args).To(Equal("IgnoreUnknown=true;K8S_POD_NAME=pod"))
+		Expect(received.StdinData).To(MatchJSON(conf))
+
+		r, convErr := current.NewResultFromResult(result)
+		Expect(convErr).NotTo(HaveOccurred())
+		Expect(r.IPs).To(HaveLen(1))
+		Expect(r.IPs[0].Address.String()).To(Equal("10.0.0.2/24"))
+	})
+
+	It("fails ADD explicitly for plugins printing with types.PrintResult", func() {
+		plugin.Funcs.Add = func(context.Context, *skel.CmdArgs) error {
+			return types.PrintResult(&current.Result{CNIVersion: current.ImplementedSpecVersion}, "1.0.0")
+		}
+
+		// Keep the result printed to os.Stdout out of the test output
+		stdout := os.Stdout
+		devNull, openErr := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+		Expect(openErr).NotTo(HaveOccurred())
+		defer devNull.Close()
+		os.Stdout = devNull
+		_, err := plugin.Add(context.TODO(), skeltest.Args{}, []byte(conf))
+		os.Stdout = stdout
+
+		Expect(err).NotTo(BeNil())
+		Expect(err.Code).To(Equal(uint(types.ErrDecodingFailure)))
+		Expect(err.Msg).To(Equal("plugin printed no result"))
+		Expect(err.Details).To(ContainSubstring("args.PrintResult"))
+	})
+
+	It("returns the error of a failing callback", func() {
+		addErr = types.NewError(types.ErrTryAgainLater, "busy", "")
+		_, err := plugin.Add(context.TODO(), skeltest.Args{}, []byte(conf))
+		Expect(err).To(Equal(types.NewError(types.ErrTryAgainLater, "busy", "")))
+	})
+
+	It("applies the dispatcher's argument validation", func() {
+		err := plugin.Del(context.TODO(), skeltest.Args{Env: map[string]string{"CNI_IFNAME": ""}}, []byte(conf))
+		Expect(err).NotTo(BeNil())
+		Expect(err.Code).To(BeEquivalentTo(types.ErrInvalidEnvironmentVariables))
+		Expect(received).To(BeNil())
+	})
+
+	It("applies the dispatcher's version checks", func() {
+		err := plugin.Del(context.TODO(), skeltest.Args{}, []byte(`{ "name": "test", "cniVersion": "1.1.0" }`))
+		Expect(err).NotTo(BeNil())
+		Expect(err.Code).To(BeEquivalentTo(types.ErrIncompatibleCNIVersion))
+	})
+
+	It("runs GC and STATUS without a container", func() {
+		var commands []string
+		record := func(command string) func(*skel.CmdArgs) error {
+			return func(args *skel.CmdArgs) error {
+				Expect(args.ContainerID).To(BeEmpty())
+				Expect(args.IfName).To(BeEmpty())
+				commands = append(commands, command)
+				return nil
+			}
+		}
+		plugin = skeltest.New(skel.CNIFuncs{GC: record("GC"), Status: record("STATUS")}, version.All)
+
+		conf := []byte(`{ "name": "test", "cniVersion": "1.1.0" }`)
+		Expect(plugin.GC(context.TODO(), skeltest.Args{}, conf)).To(BeNil())
+		Expect(plugin.Status(context.TODO(), skeltest.Args{}, conf)).To(BeNil())
+		Expect(commands).To(Equal([]string{"GC", "STATUS"}))
+	})
+
+	It("returns the versions reported by VERSION", func() {
+		info, err := plugin.Version(context.TODO())
+		Expect(err).To(BeNil())
+		Expect(info.SupportedVersions()).To(ConsistOf("0.4.0", "1.0.0"))
+	})
+
+	It("passes the context to context-aware callbacks", func() {
+		type key struct{}
+		var value interface{}
+		plugin = skeltest.NewContext(skel.CNIFuncsContext{
+			Del: func(ctx context.Context, _ *skel.CmdArgs) error {
+				value = ctx.Value(key{})
+				return nil
+			},
+		}, version.All)
+
+		ctx := context.WithValue(context.TODO(), key{}, "potato")
+		Expect(plugin.Del(ctx, skeltest.Args{}, []byte(conf))).To(BeNil())
+		Expect(value).To(Equal("potato"))
+	})
+})
+
+var _ = Describe("WithPrevResult", func() {
+	It("adds the result converted to the version of the configuration", func() {
+		ipnet, _ := types.ParseCIDR("10.0.0.2/24")
+		result := &current.Result{
+			CNIVersion: current.ImplementedSpecVersion,
+			IPs:        []*current.IPConfig{{Address: *ipnet}},
+		}
+
+		out, err := skeltest.WithPrevResult([]byte(`{ "name": "test", "cniVersion": "0.4.0" }`), result)
+		Expect(err).NotTo(HaveOccurred())
+
+		var parsed struct {
+			Name       string `json:"name"`
+			PrevResult struct {
+				CNIVersion string `json:"cniVersion"`
+				IPs        []struct {
+					Version string `json:"version"`
+					Address string `json:"address"`
+				} `json:"ips"`
+			} `json:"prevResult"`
+		}
+		Expect(json.Unmarshal(out, &parsed)).To(Succeed())
+		Expect(parsed.Name).To(Equal("test"))
+		Expect(parsed.PrevResult.CNIVersion).To(Equal("0.4.0"))
+		Expect(parsed.PrevResult.IPs).To(HaveLen(1))
+		Expect(parsed.PrevResult.IPs[0].Version).To(Equal("4"))
+		Expect(parsed.PrevResult.IPs[0].Address).To(Equal("10.0.0.2/24"))
+	})
+
+	It("fails on an invalid configuration", func() {
+		_, err := skeltest.WithPrevResult([]byte(`{`), &current.Result{CNIVersion: "1.0.0"})
+		Expect(err).To(MatchError(ContainSubstring("failed to decode network configuration")))
+	})
+})
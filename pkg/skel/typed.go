@@ -0,0 +1,143 @@
+// Copyright 2024 CNI authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package skel
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"reflect"
+
+	"github.com/containernetworking/cni/pkg/types"
+	current "github.com/containernetworking/cni/pkg/types/100"
+	"github.com/containernetworking/cni/pkg/version"
+)
+
+// TypedCmdArgs holds the arguments of a command along with their decoded
+// forms. C is the plugin's configuration type, usually a struct embedding
+// types.NetConf, and A is the type CNI_ARGS are decoded into with
+// types.LoadArgs, usually a struct embedding types.CommonArgs. Plugins that
+// do not use CNI_ARGS can use struct{} for A, for which CNI_ARGS are not
+// decoded at all.
+type TypedCmdArgs[C, A any] struct {
+	*CmdArgs
+
+	// Conf is StdinData decoded into the plugin's configuration type
+	Conf *C
+	// NetConf is StdinData decoded into the common configuration keys. Its
+	// PrevResult is set if the configuration has a prevResult.
+	NetConf *types.NetConf
+	// PrevResult is the prevResult converted to the current result
+	// version, or nil if the configuration has none
+	PrevResult *current.Result
+	// CNIArgs is CNI_ARGS decoded with types.LoadArgs
+	CNIArgs *A
+}
+
+// TypedCNIFuncs is like CNIFuncsContext, but its callbacks are passed the
+// decoded configuration, prevResult and CNI_ARGS. See TypedCmdArgs.
+type TypedCNIFuncs[C, A any] struct {
+	Add    func(ctx context.Context, args *TypedCmdArgs[C, A]) error
+	Del    func(ctx context.Context, args *TypedCmdArgs[C, A]) error
+	Check  func(ctx context.Context, args *TypedCmdArgs[C, A]) error
+	GC     func(ctx context.Context, args *TypedCmdArgs[C, A]) error
+	Status func(ctx context.Context, args *TypedCmdArgs[C, A]) error
+}
+
+// DecodeArgs decodes the configuration, prevResult and CNI_ARGS of args.
+// It returns an ErrDecodingFailure error if the configuration or
+// prevResult cannot be decoded, and an ErrInvalidEnvironmentVariables error
+// if CNI_ARGS cannot be decoded.
+func DecodeArgs[C, A any](args *CmdArgs) (*TypedCmdArgs[C, A], *types.Error) {
+	typed := &TypedCmdArgs[C, A]{
+		CmdArgs: args,
+		Conf:    new(C),
+		NetConf: &types.NetConf{},
+		CNIArgs: new(A),
+	}
+
+	if err := json.Unmarshal(args.StdinData, typed.Conf); err != nil {
+		return nil, types.NewError(types.ErrDecodingFailure, fmt.Sprintf("failed to decode network configuration: %v", err), "")
+	}
+	if err := json.Unmarshal(args.StdinData, typed.NetConf); err != nil {
+		return nil, types.NewError(types.ErrDecodingFailure, fmt.Sprintf("failed to decode network configuration: %v", err), "")
+	}
+
+	if err := version.ParsePrevResult(typed.NetConf); err != nil {
+		return nil, types.NewError(types.ErrDecodingFailure, err.Error(), "")
+	}
+	if typed.NetConf.PrevResult != nil {
+		prevResult, err := current.NewResultFromResult(typed.NetConf.PrevResult)
+		if err != nil {
+			return nil, types.NewError(types.ErrDecodingFailure, fmt.Sprintf("could not convert prevResult: %v", err), "")
+		}
+		typed.PrevResult = prevResult
+	}
+
+	if t := reflect.TypeOf(typed.CNIArgs).Elem(); t.Kind() == reflect.Struct && t.NumField() == 0 {
+		return typed, nil
+	}
+	if err := types.LoadArgs(args.Args, typed.CNIArgs); err != nil {
+		return nil, types.NewError(types.ErrInvalidEnvironmentVariables, fmt.Sprintf("failed to decode CNI_ARGS: %v", err), "")
+	}
+	return typed, nil
+}
+
+func typedFunc[C, A any](f func(context.Context, *TypedCmdArgs[C, A]) error) func(context.Context, *CmdArgs) error {
+	if f == nil {
+		return nil
+	}
+	return func(ctx context.Context, args *CmdArgs) error {
+		typed, err := DecodeArgs[C, A](args)
+		if err != nil {
+			return err
+		}
+		return f(ctx, typed)
+	}
+}
+
+func (funcs TypedCNIFuncs[C, A]) withContext() CNIFuncsContext {
+	return CNIFuncsContext{
+		Add:    typedFunc(funcs.Add),
+		Del:    typedFunc(funcs.Del),
+		Check:  typedFunc(funcs.Check),
+		GC:     typedFunc(funcs.GC),
+		Status: typedFunc(funcs.Status),
+	}
+}
+
+// PluginMainTypedWithError is like PluginMainFuncsContextWithError, but
+// decodes the configuration, prevResult and CNI_ARGS for the callbacks.
+// Decoding errors are returned without calling the callback.
+func PluginMainTypedWithError[C, A any](funcs TypedCNIFuncs[C, A], versionInfo version.PluginInfo, about string) *types.Error {
+	return PluginMainFuncsContextWithError(funcs.withContext(), versionInfo, about)
+}
+
+// PluginMainTyped is like PluginMainFuncsContext, but decodes the
+// configuration, prevResult and CNI_ARGS for the callbacks. See
+// TypedCmdArgs.
+//
+// When an error occurs, PluginMainTyped will print the error as JSON to
+// stdout and call os.Exit(1).
+func PluginMainTyped[C, A any](funcs TypedCNIFuncs[C, A], versionInfo version.PluginInfo, about string) {
+	if e := PluginMainTypedWithError(funcs, versionInfo, about); e != nil {
+		if err := e.Print(); err != nil {
+			log.Print("Error writing error JSON to stdout: ", err)
+		}
+		os.Exit(1)
+	}
+}
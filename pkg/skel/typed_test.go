@@ -0,0 +1,166 @@
+// Copyright 2024 CNI authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package skel
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/containernetworking/cni/pkg/types"
+	"github.com/containernetworking/cni/pkg/version"
+)
+
+type typedTestConf struct {
+	types.NetConf
+	Bridge string `json:"bridge"`
+}
+
+type typedTestArgs struct {
+	types.CommonArgs
+	IP net.IP
+}
+
+var _ = Describe("dispatching to typed callbacks", func() {
+	var (
+		environment map[string]string
+		stdinData   string
+		received    *TypedCmdArgs[typedTestConf, typedTestArgs]
+		callCount   int
+		funcs       TypedCNIFuncs[typedTestConf, typedTestArgs]
+	)
+
+	dispatch := func() *types.Error {
+		d := &dispatcher{
+			Getenv: func(key string) string { return environment[key] },
+			Stdin:  strings.NewReader(stdinData),
+			Stdout: &bytes.Buffer{},
+			Stderr: &bytes.Buffer{},
+		}
+		return d.pluginMainContext(context.Background(), funcs.withContext(), version.PluginSupports("1.0.0"), "")
+	}
+
+	BeforeEach(func() {
+		environment = map[string]string{
+			"CNI_COMMAND":     "ADD",
+			"CNI_CONTAINERID": "some-container-id",
+			"CNI_NETNS":       "/some/netns/path",
+			"CNI_IFNAME":      "eth0",
+			"CNI_ARGS":        "IP=10.1.2.3",
+			"CNI_PATH":        "/some/cni/path",
+		}
+		stdinData = `{
+			"cniVersion": "1.0.0",
+			"name": "skel-test",
+			"type": "typed",
+			"bridge": "cni0",
+			"prevResult": {
+				"cniVersion": "1.0.0",
+				"ips": [{"address": "10.1.2.3/24"}]
+			}
+		}`
+		received, callCount = nil, 0
+		record := func(_ context.Context, args *TypedCmdArgs[typedTestConf, typedTestArgs]) error {
+			received = args
+			callCount++
+			return nil
+		}
+		funcs = TypedCNIFuncs[typedTestConf, typedTestArgs]{Add: record, Del: record}
+	})
+
+	It("decodes the configuration, prevResult and CNI_ARGS", func() {
+		Expect(dispatch()).To(BeNil())
+		Expect(callCount).To(Equal(1))
+
+		Expect(received.ContainerID).To(Equal("some-container-id"))
+		Expect(received.Conf.Bridge).To(Equal("cni0"))
+		Expect(received.Conf.Name).To(Equal("skel-test"))
+		Expect(received.NetConf.Type).To(Equal("typed"))
+		Expect(received.NetConf.PrevResult).NotTo(BeNil())
+
+		Expect(received.PrevResult).NotTo(BeNil())
+		Expect(received.PrevResult.CNIVersion).To(Equal("1.1.0"))
+		Expect(received.PrevResult.IPs).To(HaveLen(1))
+		Expect(received.PrevResult.IPs[0].Address.String()).To(Equal("10.1.2.3/24"))
+
+		Expect(received.CNIArgs.IP.String()).To(Equal("10.1.2.3"))
+	})
+
+	It("leaves PrevResult nil when there is none", func() {
+		stdinData = `{ "cniVersion": "1.0.0", "name": "skel-test" }`
+		Expect(dispatch()).To(BeNil())
+		Expect(received.PrevResult).To(BeNil())
+	})
+
+	It("returns a decoding error for an invalid configuration", func() {
+		stdinData = `{ "cniVersion": "1.0.0", "name": "skel-test", "bridge": 5 }`
+		err := dispatch()
+		Expect(err).NotTo(BeNil())
+		Expect(err.Code).To(Equal(uint(types.ErrDecodingFailure)))
+		Expect(err.Msg).To(HavePrefix("failed to decode network configuration:"))
+		Expect(callCount).To(BeZero())
+	})
+
+	It("returns a decoding error for an invalid prevResult", func() {
+		stdinData = `{ "cniVersion": "1.0.0", "name": "skel-test", "prevResult": { "ips": [{"address": "potato"}] } }`
+		err := dispatch()
+		Expect(err).NotTo(BeNil())
+		Expect(err.Code).To(Equal(uint(types.ErrDecodingFailure)))
+		Expect(err.Msg).To(HavePrefix("could not parse prevResult"))
+		Expect(callCount).To(BeZero())
+	})
+
+	It("returns an environment error for invalid CNI_ARGS", func() {
+		environment["CNI_ARGS"] = "IP=10.1.2.3;FOO=bar"
+		err := dispatch()
+		Expect(err).NotTo(BeNil())
+		Expect(err.Code).To(Equal(uint(types.ErrInvalidEnvironmentVariables)))
+		Expect(err.Msg).To(Equal(`failed to decode CNI_ARGS: ARGS: unknown args ["FOO=bar"]`))
+		Expect(callCount).To(BeZero())
+
+		environment["CNI_ARGS"] = "IgnoreUnknown=1;IP=10.1.2.3;FOO=bar"
+		Expect(dispatch()).To(BeNil())
+	})
+
+	It("does not decode CNI_ARGS into struct{}", func() {
+		environment["CNI_ARGS"] = "FOO=bar"
+		var conf *typedTestConf
+		noArgs := TypedCNIFuncs[typedTestConf, struct{}]{
+			Add: func(_ context.Context, args *TypedCmdArgs[typedTestConf, struct{}]) error {
+				conf = args.Conf
+				return nil
+			},
+		}
+		d := &dispatcher{
+			Getenv: func(key string) string { return environment[key] },
+			Stdin:  strings.NewReader(stdinData),
+			Stdout: &bytes.Buffer{},
+			Stderr: &bytes.Buffer{},
+		}
+		Expect(d.pluginMainContext(context.Background(), noArgs.withContext(), version.PluginSupports("1.0.0"), "")).To(BeNil())
+		Expect(conf.Bridge).To(Equal("cni0"))
+	})
+
+	It("checks the version before decoding", func() {
+		stdinData = `{ "cniVersion": "0.4.0", "name": "skel-test", "bridge": 5 }`
+		err := dispatch()
+		Expect(err).NotTo(BeNil())
+		Expect(err.Code).To(Equal(uint(types.ErrIncompatibleCNIVersion)))
+	})
+})
@@ -16,6 +16,7 @@ package types020
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net"
@@ -122,6 +123,13 @@ func (r *Result) GetAsVersion(version string) (types.Result, error) {
 	return convert.Convert(r, version)
 }
 
+func (r *Result) GetAsVersionWithWarnings(version string) (types.Result, []types.ConversionWarning, error) {
+	if r.CNIVersion == "" {
+		r.CNIVersion = ImplementedSpecVersion
+	}
+	return convert.ConvertWithWarnings(r, version)
+}
+
 func (r *Result) Print() error {
 	return r.PrintTo(os.Stdout)
 }
@@ -135,6 +143,37 @@ func (r *Result) PrintTo(writer io.Writer) error {
 	return err
 }
 
+// Validate checks that the result is semantically valid: the ip4 and ip6
+// configurations hold masked addresses of their respective family with a
+// gateway of the same family, and their routes have a destination with a
+// gateway of the same family. All problems found are returned.
+func (r *Result) Validate() error {
+	var errs []error
+	for _, ipc := range []struct {
+		name string
+		ipv4 bool
+		conf *IPConfig
+	}{{"ip4", true, r.IP4}, {"ip6", false, r.IP6}} {
+		if ipc.conf == nil {
+			continue
+		}
+		if err := convert.ValidateIPNet(&ipc.conf.IP); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", ipc.name, err))
+		} else if (ipc.conf.IP.IP.To4() != nil) != ipc.ipv4 {
+			errs = append(errs, fmt.Errorf("%s: address %v is in the wrong family", ipc.name, ipc.conf.IP.IP))
+		}
+		if err := convert.ValidateGateway(ipc.conf.Gateway, ipc.conf.IP.IP); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", ipc.name, err))
+		}
+		for i := range ipc.conf.Routes {
+			if err := convert.ValidateRoute(&ipc.conf.Routes[i]); err != nil {
+				errs = append(errs, fmt.Errorf("%s: routes[%d]: %w", ipc.name, i, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
 // IPConfig contains values necessary to configure an interface
 type IPConfig struct {
 	IP      net.IPNet
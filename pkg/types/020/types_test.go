@@ -154,4 +154,21 @@ var _ = Describe("Ensures compatibility with the 0.1.0/0.2.0 spec", func() {
 		Expect(ok).To(BeTrue())
 		Expect(res010.CNIVersion).To(Equal("0.1.0"))
 	})
+
+	Describe("Validate", func() {
+		It("accepts a valid result", func() {
+			res, _ := testResult(types020.ImplementedSpecVersion, types020.ImplementedSpecVersion)
+			Expect(res.Validate()).To(Succeed())
+		})
+
+		It("rejects addresses and routes in the wrong family", func() {
+			res, _ := testResult(types020.ImplementedSpecVersion, types020.ImplementedSpecVersion)
+			res.IP4.IP = res.IP6.IP
+			res.IP6.Routes[0].GW = net.ParseIP("1.2.3.4")
+			Expect(res.Validate()).To(MatchError(And(
+				ContainSubstring("ip4: address abcd:1234:ffff::cdde is in the wrong family"),
+				ContainSubstring("ip6: routes[0]: IPv4 gateway 1.2.3.4 for IPv6 address 1111:dddd::"),
+			)))
+		})
+	})
 })
@@ -16,6 +16,7 @@ package types040
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net"
@@ -204,6 +205,13 @@ func (r *Result) GetAsVersion(version string) (types.Result, error) {
 	return convert.Convert(r, version)
 }
 
+func (r *Result) GetAsVersionWithWarnings(version string) (types.Result, []types.ConversionWarning, error) {
+	if r.CNIVersion == "" {
+		r.CNIVersion = ImplementedSpecVersion
+	}
+	return convert.ConvertWithWarnings(r, version)
+}
+
 func (r *Result) Print() error {
 	return r.PrintTo(os.Stdout)
 }
@@ -217,6 +225,51 @@ func (r *Result) PrintTo(writer io.Writer) error {
 	return err
 }
 
+// Validate checks that the result is semantically valid: every interface
+// has a name, every IP configuration refers to an existing interface and
+// has a masked address matching its version with a gateway of the same
+// family, and every route has a destination with a gateway of the same
+// family. All problems found are returned.
+func (r *Result) Validate() error {
+	var errs []error
+	for i, intf := range r.Interfaces {
+		if intf == nil {
+			errs = append(errs, fmt.Errorf("interfaces[%d]: missing interface", i))
+		} else if intf.Name == "" {
+			errs = append(errs, fmt.Errorf("interfaces[%d]: missing interface name", i))
+		}
+	}
+	for i, ipc := range r.IPs {
+		if ipc == nil {
+			errs = append(errs, fmt.Errorf("ips[%d]: missing IP configuration", i))
+			continue
+		}
+		if err := convert.ValidateInterfaceIndex(ipc.Interface, len(r.Interfaces)); err != nil {
+			errs = append(errs, fmt.Errorf("ips[%d]: %w", i, err))
+		}
+		if err := convert.ValidateIPNet(&ipc.Address); err != nil {
+			errs = append(errs, fmt.Errorf("ips[%d]: %w", i, err))
+		} else {
+			version := "6"
+			if ipc.Address.IP.To4() != nil {
+				version = "4"
+			}
+			if ipc.Version != version {
+				errs = append(errs, fmt.Errorf("ips[%d]: version %q does not match address %v", i, ipc.Version, ipc.Address.IP))
+			}
+		}
+		if err := convert.ValidateGateway(ipc.Gateway, ipc.Address.IP); err != nil {
+			errs = append(errs, fmt.Errorf("ips[%d]: %w", i, err))
+		}
+	}
+	for i, route := range r.Routes {
+		if err := convert.ValidateRoute(route); err != nil {
+			errs = append(errs, fmt.Errorf("routes[%d]: %w", i, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
 // Interface contains values about the created interfaces
 type Interface struct {
 	Name    string `json:"name"`
@@ -384,4 +384,22 @@ var _ = Describe("040 types operations", func() {
     "address": "10.1.2.3/24"
 }`))
 	})
+
+	Describe("Validate", func() {
+		It("accepts a valid result", func() {
+			Expect(testResult().Validate()).To(Succeed())
+		})
+
+		It("rejects an IP version that does not match the address", func() {
+			res := testResult()
+			res.IPs[0].Version = "6"
+			Expect(res.Validate()).To(MatchError(`ips[0]: version "6" does not match address 1.2.3.30`))
+		})
+
+		It("rejects an out of range interface index", func() {
+			res := testResult()
+			res.IPs[1].Interface = types040.Int(-1)
+			Expect(res.Validate()).To(MatchError("ips[1]: interface index -1 is out of range (result has 1 interfaces)"))
+		})
+	})
 })
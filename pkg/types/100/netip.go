@@ -0,0 +1,71 @@
+// Copyright 2024 CNI authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types100
+
+import (
+	"net/netip"
+
+	"github.com/containernetworking/cni/pkg/types"
+)
+
+// NewIPConfig returns an IP configuration of address, with host bits, on
+// the interface at index intf, which may be nil. gw may be the zero Addr
+// for an IP configuration without a gateway.
+func NewIPConfig(intf *int, address netip.Prefix, gw netip.Addr) *IPConfig {
+	ipc := &IPConfig{
+		Address: types.IPNetFromPrefix(address),
+		Gateway: types.IPFromAddr(gw),
+	}
+	if intf != nil {
+		ipc.Interface = Int(*intf)
+	}
+	return ipc
+}
+
+// AddressPrefix returns the address of the IP configuration, with host
+// bits, as a netip.Prefix.
+func (i *IPConfig) AddressPrefix() netip.Prefix {
+	return types.PrefixFromIPNet(i.Address)
+}
+
+// GatewayAddr returns the gateway of the IP configuration as a netip.Addr,
+// or the zero Addr if it has no gateway.
+func (i *IPConfig) GatewayAddr() netip.Addr {
+	return types.AddrFromIP(i.Gateway)
+}
+
+// Addresses returns the addresses of all IP configurations in the result.
+func (r *Result) Addresses() []netip.Prefix {
+	return addressPrefixes(r.IPs)
+}
+
+// SandboxAddresses returns the addresses of the IP configurations selected
+// by SandboxIPs.
+func (r *Result) SandboxAddresses() []netip.Prefix {
+	return addressPrefixes(r.SandboxIPs())
+}
+
+func addressPrefixes(ips []*IPConfig) []netip.Prefix {
+	var prefixes []netip.Prefix
+	for _, ipc := range ips {
+		if ipc == nil {
+			continue
+		}
+		if p := ipc.AddressPrefix(); p.IsValid() {
+			prefixes = append(prefixes, p)
+		}
+	}
+	return prefixes
+}
@@ -0,0 +1,76 @@
+// Copyright 2024 CNI authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types100_test
+
+import (
+	"encoding/json"
+	"net/netip"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	current "github.com/containernetworking/cni/pkg/types/100"
+)
+
+var _ = Describe("netip accessors", func() {
+	It("builds an IP configuration from netip values", func() {
+		ipc := current.NewIPConfig(current.Int(0), netip.MustParsePrefix("10.1.2.3/24"), netip.MustParseAddr("10.1.2.1"))
+		Expect(ipc).To(Equal(mustIPConfig(current.Int(0), "10.1.2.3/24", "10.1.2.1")))
+		Expect(ipc.AddressPrefix()).To(Equal(netip.MustParsePrefix("10.1.2.3/24")))
+		Expect(ipc.GatewayAddr()).To(Equal(netip.MustParseAddr("10.1.2.1")))
+
+		jsonBytes, err := json.Marshal(ipc)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(jsonBytes).To(MatchJSON(`{
+    "interface": 0,
+    "address": "10.1.2.3/24",
+    "gateway": "10.1.2.1"
+}`))
+
+		recovered := &current.IPConfig{}
+		Expect(json.Unmarshal(jsonBytes, recovered)).To(Succeed())
+		Expect(recovered).To(Equal(ipc))
+	})
+
+	It("builds an IP configuration without an interface or gateway", func() {
+		ipc := current.NewIPConfig(nil, netip.MustParsePrefix("fd00::3/64"), netip.Addr{})
+		Expect(ipc.Interface).To(BeNil())
+		Expect(ipc.Gateway).To(BeNil())
+		Expect(ipc.GatewayAddr().IsValid()).To(BeFalse())
+	})
+
+	It("returns the addresses of a result", func() {
+		result := &current.Result{
+			Interfaces: []*current.Interface{
+				{Name: "cni0"},
+				{Name: "eth0", Sandbox: "/var/run/netns/test"},
+			},
+			IPs: []*current.IPConfig{
+				mustIPConfig(current.Int(1), "10.1.2.3/24", "10.1.2.1"),
+				mustIPConfig(current.Int(0), "10.1.2.1/24", ""),
+				mustIPConfig(current.Int(1), "fd00::3/64", ""),
+			},
+		}
+		Expect(result.Addresses()).To(Equal([]netip.Prefix{
+			netip.MustParsePrefix("10.1.2.3/24"),
+			netip.MustParsePrefix("10.1.2.1/24"),
+			netip.MustParsePrefix("fd00::3/64"),
+		}))
+		Expect(result.SandboxAddresses()).To(Equal([]netip.Prefix{
+			netip.MustParsePrefix("10.1.2.3/24"),
+			netip.MustParsePrefix("fd00::3/64"),
+		}))
+	})
+})
@@ -0,0 +1,177 @@
+// Copyright 2024 CNI authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types100
+
+import (
+	"net"
+)
+
+// SandboxInterface returns the index and value of the first interface in
+// the result that lives in a sandbox (i.e. has a non-empty Sandbox), or
+// -1 and nil if there is none.
+func (r *Result) SandboxInterface() (int, *Interface) {
+	for i, intf := range r.Interfaces {
+		if intf != nil && intf.Sandbox != "" {
+			return i, intf
+		}
+	}
+	return -1, nil
+}
+
+// HostInterface returns the index and value of the host side of the
+// sandbox interface, e.g. the host end of a veth pair, or -1 and nil if
+// there is none.
+//
+// Following the convention of the reference plugins, this is the last
+// interface without a Sandbox listed before the sandbox interface. When
+// the result has no sandbox interface, it is the last interface without a
+// Sandbox.
+func (r *Result) HostInterface() (int, *Interface) {
+	end, _ := r.SandboxInterface()
+	if end < 0 {
+		end = len(r.Interfaces)
+	}
+	for i := end - 1; i >= 0; i-- {
+		if intf := r.Interfaces[i]; intf != nil && intf.Sandbox == "" {
+			return i, intf
+		}
+	}
+	return -1, nil
+}
+
+// InterfaceByName returns the index and value of the first interface with
+// the given name and sandbox, or -1 and nil if there is none. An empty
+// sandbox matches interfaces in the host namespace.
+func (r *Result) InterfaceByName(name, sandbox string) (int, *Interface) {
+	for i, intf := range r.Interfaces {
+		if intf != nil && intf.Name == name && intf.Sandbox == sandbox {
+			return i, intf
+		}
+	}
+	return -1, nil
+}
+
+// IPsForInterface returns the IP configurations bound to the interface at
+// the given index of Interfaces. IP configurations without an interface
+// index, or whose index is outside of Interfaces, are never returned.
+func (r *Result) IPsForInterface(index int) []*IPConfig {
+	var ips []*IPConfig
+	if index < 0 || index >= len(r.Interfaces) {
+		return ips
+	}
+	for _, ipc := range r.IPs {
+		if ipc != nil && ipc.Interface != nil && *ipc.Interface == index {
+			ips = append(ips, ipc)
+		}
+	}
+	return ips
+}
+
+// SandboxIPs returns the IP configurations of the sandbox interface.
+//
+// IP configurations without an interface index are assumed to belong to
+// the sandbox, as is the case for results produced by IPAM plugins. IP
+// configurations whose index is outside of Interfaces are ignored.
+func (r *Result) SandboxIPs() []*IPConfig {
+	sandboxIdx, _ := r.SandboxInterface()
+
+	var ips []*IPConfig
+	for _, ipc := range r.IPs {
+		if ipc == nil {
+			continue
+		}
+		if ipc.Interface == nil || (sandboxIdx >= 0 && *ipc.Interface == sandboxIdx) {
+			ips = append(ips, ipc)
+		}
+	}
+	return ips
+}
+
+// SandboxIPv4s returns the IPv4 configurations of the sandbox interface,
+// as selected by SandboxIPs.
+func (r *Result) SandboxIPv4s() []*IPConfig {
+	return filterIPs(r.SandboxIPs(), false)
+}
+
+// SandboxIPv6s returns the IPv6 configurations of the sandbox interface,
+// as selected by SandboxIPs.
+func (r *Result) SandboxIPv6s() []*IPConfig {
+	return filterIPs(r.SandboxIPs(), true)
+}
+
+// DefaultGatewayV4 returns the IPv4 default gateway of the sandbox, or nil
+// if there is none. See DefaultGateway.
+func (r *Result) DefaultGatewayV4() net.IP {
+	return r.defaultGateway(false)
+}
+
+// DefaultGatewayV6 returns the IPv6 default gateway of the sandbox, or nil
+// if there is none. See DefaultGateway.
+func (r *Result) DefaultGatewayV6() net.IP {
+	return r.defaultGateway(true)
+}
+
+// DefaultGateway returns the IPv4 default gateway of the sandbox, or its
+// IPv6 default gateway if there is no IPv4 one, or nil if there is none.
+//
+// The gateway of a default route (0.0.0.0/0 or ::/0) takes precedence.
+// A default route without a gateway, or no default route at all, falls
+// back to the gateway of the first sandbox IP configuration of the same
+// family.
+func (r *Result) DefaultGateway() net.IP {
+	if gw := r.DefaultGatewayV4(); gw != nil {
+		return gw
+	}
+	return r.DefaultGatewayV6()
+}
+
+func (r *Result) defaultGateway(ipv6 bool) net.IP {
+	for _, route := range r.Routes {
+		if route == nil || !isDefaultRoute(&route.Dst, ipv6) {
+			continue
+		}
+		if route.GW != nil && isIPv6(route.GW) == ipv6 {
+			return route.GW
+		}
+	}
+	for _, ipc := range filterIPs(r.SandboxIPs(), ipv6) {
+		if ipc.Gateway != nil && isIPv6(ipc.Gateway) == ipv6 {
+			return ipc.Gateway
+		}
+	}
+	return nil
+}
+
+func isDefaultRoute(dst *net.IPNet, ipv6 bool) bool {
+	if dst.IP == nil || isIPv6(dst.IP) != ipv6 {
+		return false
+	}
+	ones, _ := dst.Mask.Size()
+	return ones == 0 && dst.IP.IsUnspecified()
+}
+
+func isIPv6(ip net.IP) bool {
+	return ip.To4() == nil
+}
+
+func filterIPs(ips []*IPConfig, ipv6 bool) []*IPConfig {
+	var out []*IPConfig
+	for _, ipc := range ips {
+		if ipc.Address.IP != nil && isIPv6(ipc.Address.IP) == ipv6 {
+			out = append(out, ipc)
+		}
+	}
+	return out
+}
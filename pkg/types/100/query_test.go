@@ -0,0 +1,152 @@
+// Copyright 2024 CNI authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types100_test
+
+import (
+	"net"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/containernetworking/cni/pkg/types"
+	current "github.com/containernetworking/cni/pkg/types/100"
+)
+
+func mustIPConfig(intf *int, cidr, gw string) *current.IPConfig {
+	addr, err := types.ParseCIDR(cidr)
+	Expect(err).NotTo(HaveOccurred())
+	return &current.IPConfig{
+		Interface: intf,
+		Address:   *addr,
+		Gateway:   net.ParseIP(gw),
+	}
+}
+
+func mustRoute(dst, gw string) *types.Route {
+	_, dstNet, err := net.ParseCIDR(dst)
+	Expect(err).NotTo(HaveOccurred())
+	return &types.Route{Dst: *dstNet, GW: net.ParseIP(gw)}
+}
+
+var _ = Describe("Result queries", func() {
+	var result *current.Result
+
+	BeforeEach(func() {
+		// Shaped like the output of the bridge plugin on a dual-stack network
+		result = &current.Result{
+			CNIVersion: current.ImplementedSpecVersion,
+			Interfaces: []*current.Interface{
+				{Name: "cni0", Mac: "00:11:22:33:44:00"},
+				{Name: "veth1234", Mac: "00:11:22:33:44:01"},
+				{Name: "eth0", Mac: "00:11:22:33:44:02", Sandbox: "/var/run/netns/test"},
+			},
+			IPs: []*current.IPConfig{
+				mustIPConfig(current.Int(2), "10.1.2.3/24", "10.1.2.1"),
+				mustIPConfig(current.Int(2), "fd00::3/64", "fd00::1"),
+				mustIPConfig(current.Int(0), "10.1.2.1/24", ""),
+			},
+			Routes: []*types.Route{
+				mustRoute("0.0.0.0/0", "10.1.2.254"),
+				mustRoute("::/0", ""),
+			},
+		}
+	})
+
+	It("finds the sandbox interface", func() {
+		idx, intf := result.SandboxInterface()
+		Expect(idx).To(Equal(2))
+		Expect(intf.Name).To(Equal("eth0"))
+	})
+
+	It("finds the host side of the sandbox interface", func() {
+		idx, intf := result.HostInterface()
+		Expect(idx).To(Equal(1))
+		Expect(intf.Name).To(Equal("veth1234"))
+	})
+
+	It("finds an interface by name and sandbox", func() {
+		idx, intf := result.InterfaceByName("eth0", "/var/run/netns/test")
+		Expect(idx).To(Equal(2))
+		Expect(intf).To(Equal(result.Interfaces[2]))
+
+		idx, intf = result.InterfaceByName("eth0", "")
+		Expect(idx).To(Equal(-1))
+		Expect(intf).To(BeNil())
+	})
+
+	It("returns the addresses of the sandbox interface by family", func() {
+		Expect(result.SandboxIPs()).To(Equal(result.IPs[:2]))
+		Expect(result.SandboxIPv4s()).To(Equal(result.IPs[:1]))
+		Expect(result.SandboxIPv6s()).To(Equal(result.IPs[1:2]))
+		Expect(result.IPsForInterface(0)).To(Equal(result.IPs[2:]))
+	})
+
+	It("returns the default gateway of each family", func() {
+		// from the default route
+		Expect(result.DefaultGatewayV4().String()).To(Equal("10.1.2.254"))
+		// the IPv6 default route has no gateway, so use the address's
+		Expect(result.DefaultGatewayV6().String()).To(Equal("fd00::1"))
+		Expect(result.DefaultGateway().String()).To(Equal("10.1.2.254"))
+	})
+
+	It("falls back to IPv6 for the default gateway of an IPv6-only result", func() {
+		result.IPs = result.IPs[1:2]
+		result.Routes = nil
+		Expect(result.DefaultGatewayV4()).To(BeNil())
+		Expect(result.DefaultGateway().String()).To(Equal("fd00::1"))
+	})
+
+	Context("when interface indices are missing", func() {
+		BeforeEach(func() {
+			result.Interfaces = nil
+			result.IPs = []*current.IPConfig{
+				mustIPConfig(nil, "10.1.2.3/24", "10.1.2.1"),
+			}
+			result.Routes = nil
+		})
+
+		It("attributes the addresses to the sandbox", func() {
+			idx, intf := result.SandboxInterface()
+			Expect(idx).To(Equal(-1))
+			Expect(intf).To(BeNil())
+			Expect(result.SandboxIPv4s()).To(Equal(result.IPs))
+			Expect(result.DefaultGatewayV4().String()).To(Equal("10.1.2.1"))
+			Expect(result.IPsForInterface(0)).To(BeEmpty())
+		})
+	})
+
+	Context("when interface indices are out of range", func() {
+		BeforeEach(func() {
+			result.IPs = []*current.IPConfig{
+				mustIPConfig(current.Int(7), "10.1.2.3/24", "10.1.2.1"),
+				mustIPConfig(current.Int(-1), "10.1.2.4/24", "10.1.2.1"),
+			}
+		})
+
+		It("ignores those addresses", func() {
+			Expect(result.SandboxIPs()).To(BeEmpty())
+			Expect(result.IPsForInterface(7)).To(BeEmpty())
+			Expect(result.IPsForInterface(-1)).To(BeEmpty())
+		})
+	})
+
+	It("handles a result without a sandbox interface", func() {
+		result.Interfaces = result.Interfaces[:2]
+		idx, intf := result.HostInterface()
+		Expect(idx).To(Equal(1))
+		Expect(intf.Name).To(Equal("veth1234"))
+		Expect(result.SandboxIPs()).To(BeEmpty())
+	})
+})
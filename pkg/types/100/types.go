@@ -16,6 +16,7 @@ package types100
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net"
@@ -26,7 +27,8 @@ import (
 	convert "github.com/containernetworking/cni/pkg/types/internal"
 )
 
-// The types did not change between v1.0 and v1.1
+// v1.1 added optional interface and route fields; they are dropped when
+// converting a result to an older version
 const ImplementedSpecVersion string = "1.1.0"
 
 var supportedVersions = []string{"1.0.0", "1.1.0"}
@@ -101,20 +103,52 @@ type Result struct {
 	DNS        types.DNS      `json:"dns,omitempty"`
 }
 
-// convertFrom100 does nothing except set the version; the types are the same
+// convertFrom100 converts between 1.0.0 and 1.1.0 results, which differ
+// only in the fields added in 1.1.0
 func convertFrom100(from types.Result, toVersion string) (types.Result, error) {
 	fromResult := from.(*Result)
 
 	result := &Result{
 		CNIVersion: toVersion,
-		Interfaces: fromResult.Interfaces,
-		IPs:        fromResult.IPs,
-		Routes:     fromResult.Routes,
-		DNS:        fromResult.DNS,
+		DNS:        *fromResult.DNS.Copy(),
+	}
+	for _, intf := range fromResult.Interfaces {
+		result.Interfaces = append(result.Interfaces, intf.Copy())
+	}
+	for _, ipc := range fromResult.IPs {
+		result.IPs = append(result.IPs, ipc.Copy())
+	}
+	for _, route := range fromResult.Routes {
+		result.Routes = append(result.Routes, route.Copy())
+	}
+
+	if toVersion == "1.0.0" {
+		for _, intf := range result.Interfaces {
+			if intf != nil {
+				intf.Mtu = 0
+				intf.SocketPath = ""
+				intf.PciID = ""
+			}
+		}
+		for _, route := range result.Routes {
+			stripRoute11(route)
+		}
 	}
 	return result, nil
 }
 
+// stripRoute11 clears the route fields added in spec version 1.1.0
+func stripRoute11(route *types.Route) {
+	if route == nil {
+		return
+	}
+	route.MTU = 0
+	route.AdvMSS = 0
+	route.Priority = 0
+	route.Table = nil
+	route.Scope = nil
+}
+
 func convertFrom02x(from types.Result, toVersion string) (types.Result, error) {
 	result040, err := convert.Convert(from, "0.4.0")
 	if err != nil {
@@ -205,7 +239,9 @@ func convertTo04x(from types.Result, toVersion string) (types.Result, error) {
 		toResult.IPs = append(toResult.IPs, convertIPConfigTo040(fromIPC))
 	}
 	for _, fromRoute := range fromResult.Routes {
-		toResult.Routes = append(toResult.Routes, fromRoute.Copy())
+		toRoute := fromRoute.Copy()
+		stripRoute11(toRoute)
+		toResult.Routes = append(toResult.Routes, toRoute)
 	}
 	return toResult, nil
 }
@@ -236,6 +272,13 @@ func (r *Result) GetAsVersion(version string) (types.Result, error) {
 	return convert.Convert(r, version)
 }
 
+func (r *Result) GetAsVersionWithWarnings(version string) (types.Result, []types.ConversionWarning, error) {
+	if r.CNIVersion == "" {
+		r.CNIVersion = ImplementedSpecVersion
+	}
+	return convert.ConvertWithWarnings(r, version)
+}
+
 func (r *Result) Print() error {
 	return r.PrintTo(os.Stdout)
 }
@@ -249,11 +292,51 @@ func (r *Result) PrintTo(writer io.Writer) error {
 	return err
 }
 
+// Validate checks that the result is semantically valid: every interface
+// has a name, every IP configuration refers to an existing interface and
+// has a masked address with a gateway of the same family, and every route
+// has a destination with a gateway of the same family. All problems found
+// are returned.
+func (r *Result) Validate() error {
+	var errs []error
+	for i, intf := range r.Interfaces {
+		if intf == nil {
+			errs = append(errs, fmt.Errorf("interfaces[%d]: missing interface", i))
+		} else if intf.Name == "" {
+			errs = append(errs, fmt.Errorf("interfaces[%d]: missing interface name", i))
+		}
+	}
+	for i, ipc := range r.IPs {
+		if ipc == nil {
+			errs = append(errs, fmt.Errorf("ips[%d]: missing IP configuration", i))
+			continue
+		}
+		if err := convert.ValidateInterfaceIndex(ipc.Interface, len(r.Interfaces)); err != nil {
+			errs = append(errs, fmt.Errorf("ips[%d]: %w", i, err))
+		}
+		if err := convert.ValidateIPNet(&ipc.Address); err != nil {
+			errs = append(errs, fmt.Errorf("ips[%d]: %w", i, err))
+		}
+		if err := convert.ValidateGateway(ipc.Gateway, ipc.Address.IP); err != nil {
+			errs = append(errs, fmt.Errorf("ips[%d]: %w", i, err))
+		}
+	}
+	for i, route := range r.Routes {
+		if err := convert.ValidateRoute(route); err != nil {
+			errs = append(errs, fmt.Errorf("routes[%d]: %w", i, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
 // Interface contains values about the created interfaces
 type Interface struct {
-	Name    string `json:"name"`
-	Mac     string `json:"mac,omitempty"`
-	Sandbox string `json:"sandbox,omitempty"`
+	Name       string `json:"name"`
+	Mac        string `json:"mac,omitempty"`
+	Mtu        int    `json:"mtu,omitempty"`
+	Sandbox    string `json:"sandbox,omitempty"`
+	SocketPath string `json:"socketPath,omitempty"`
+	PciID      string `json:"pciID,omitempty"`
 }
 
 func (i *Interface) String() string {
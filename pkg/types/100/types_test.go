@@ -19,6 +19,7 @@ import (
 	"io"
 	"net"
 	"os"
+	"strings"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -165,6 +166,133 @@ var _ = Describe("Current types operations", func() {
 		Expect(trv11).To(Equal(testResult()))
 	})
 
+	Describe("fields added in spec version 1.1.0", func() {
+		var res *current.Result
+
+		BeforeEach(func() {
+			res = testResult()
+			res.Interfaces[0].Mtu = 1500
+			res.Interfaces[0].SocketPath = "/var/run/vhost-user.sock"
+			res.Interfaces[0].PciID = "0000:00:1f.6"
+			table, scope := 100, 0
+			res.Routes[0].MTU = 1450
+			res.Routes[0].AdvMSS = 1400
+			res.Routes[0].Priority = 10
+			res.Routes[0].Table = &table
+			res.Routes[0].Scope = &scope
+		})
+
+		It("round-trips through JSON", func() {
+			jsonBytes, err := json.Marshal(res)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(jsonBytes).To(ContainSubstring(`"mtu":1500,"sandbox":"/proc/3553/ns/net","socketPath":"/var/run/vhost-user.sock","pciID":"0000:00:1f.6"`))
+			Expect(jsonBytes).To(ContainSubstring(`"mtu":1450,"advmss":1400,"priority":10,"table":100,"scope":0`))
+
+			recovered, err := current.NewResult(jsonBytes)
+			Expect(err).NotTo(HaveOccurred())
+			recoveredBytes, err := json.Marshal(recovered)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(recoveredBytes).To(MatchJSON(jsonBytes))
+			Expect(recovered.(*current.Result).Interfaces).To(Equal(res.Interfaces))
+			Expect(*recovered.(*current.Result).Routes[0].Table).To(Equal(100))
+			Expect(*recovered.(*current.Result).Routes[0].Scope).To(Equal(0))
+		})
+
+		It("does not modify the original result when converting", func() {
+			_, err := res.GetAsVersion("1.0.0")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res.Interfaces[0].Mtu).To(Equal(1500))
+			Expect(res.Routes[0].Table).NotTo(BeNil())
+		})
+
+		DescribeTable("conversion between versions",
+			func(fromVersion, toVersion string, keep bool) {
+				from, err := res.GetAsVersion(fromVersion)
+				Expect(err).NotTo(HaveOccurred())
+				to, err := from.GetAsVersion(toVersion)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(to.Version()).To(Equal(toVersion))
+
+				// Bring the result back to 1.1.0 to inspect it
+				back, err := to.GetAsVersion(current.ImplementedSpecVersion)
+				Expect(err).NotTo(HaveOccurred())
+				result := back.(*current.Result)
+				Expect(result.Routes).NotTo(BeEmpty())
+				Expect(result.Routes[0].Dst).To(Equal(res.Routes[0].Dst))
+				Expect(result.Routes[0].GW).To(Equal(res.Routes[0].GW))
+
+				if keep {
+					Expect(result).To(Equal(res))
+					return
+				}
+				for _, route := range result.Routes {
+					Expect(route.MTU).To(BeZero())
+					Expect(route.AdvMSS).To(BeZero())
+					Expect(route.Priority).To(BeZero())
+					Expect(route.Table).To(BeNil())
+					Expect(route.Scope).To(BeNil())
+				}
+				for _, intf := range result.Interfaces {
+					Expect(intf.Mtu).To(BeZero())
+					Expect(intf.SocketPath).To(BeEmpty())
+					Expect(intf.PciID).To(BeEmpty())
+				}
+			},
+			Entry("1.1.0 to 1.1.0", "1.1.0", "1.1.0", true),
+			Entry("1.1.0 to 1.0.0", "1.1.0", "1.0.0", false),
+			Entry("1.1.0 to 0.4.0", "1.1.0", "0.4.0", false),
+			Entry("1.1.0 to 0.3.1", "1.1.0", "0.3.1", false),
+			Entry("1.1.0 to 0.3.0", "1.1.0", "0.3.0", false),
+			Entry("1.1.0 to 0.2.0", "1.1.0", "0.2.0", false),
+			Entry("1.1.0 to 0.1.0", "1.1.0", "0.1.0", false),
+			Entry("1.0.0 to 1.1.0", "1.0.0", "1.1.0", false),
+			Entry("0.4.0 to 1.0.0", "0.4.0", "1.0.0", false),
+			Entry("0.2.0 to 0.4.0", "0.2.0", "0.4.0", false),
+		)
+	})
+
+	Describe("conversion warnings", func() {
+		It("reports nothing for a lossless conversion", func() {
+			_, warnings, err := testResult().GetAsVersionWithWarnings("0.4.0")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(warnings).To(BeEmpty())
+		})
+
+		It("reports the fields added in 1.1.0", func() {
+			res := testResult()
+			res.Interfaces[0].Mtu = 1500
+			res.Routes[1].Priority = 10
+			newRes, warnings, err := types.GetAsVersionWithWarnings(res, "1.0.0")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(newRes.Version()).To(Equal("1.0.0"))
+			Expect(warnings).To(Equal([]types.ConversionWarning{
+				{FromVersion: "1.1.0", ToVersion: "1.0.0", Field: "interfaces[0].mtu", Message: "was dropped"},
+				{FromVersion: "1.1.0", ToVersion: "1.0.0", Field: "routes[1].priority", Message: "was dropped"},
+			}))
+		})
+
+		It("reports what cannot be represented in 0.2.0", func() {
+			res := testResult()
+			ipc := mustIPConfig(current.Int(0), "1.2.3.31/24", "1.2.3.1")
+			res.IPs = append([]*current.IPConfig{ipc}, res.IPs...)
+			_, warnings, err := res.GetAsVersionWithWarnings("0.2.0")
+			Expect(err).NotTo(HaveOccurred())
+
+			var fields []string
+			for _, w := range warnings {
+				Expect(w.FromVersion).To(Equal("1.1.0"))
+				Expect(w.ToVersion).To(Equal("0.2.0"))
+				fields = append(fields, w.Field)
+			}
+			Expect(fields).To(Equal([]string{
+				"interfaces",
+				"ips[0].interface",
+				"ips[1]",
+				"ips[2].interface",
+			}))
+		})
+	})
+
 	It("correctly encodes a 0.1.0 Result", func() {
 		res, err := testResult().GetAsVersion("0.1.0")
 		Expect(err).NotTo(HaveOccurred())
@@ -337,4 +465,35 @@ var _ = Describe("Current types operations", func() {
     "address": "10.1.2.3/24"
 }`))
 	})
+
+	Describe("Validate", func() {
+		It("accepts a valid result", func() {
+			Expect(testResult().Validate()).To(Succeed())
+		})
+
+		It("reports every problem with an invalid result", func() {
+			res := testResult()
+			res.Interfaces = append(res.Interfaces, &current.Interface{})
+			res.IPs[0].Interface = current.Int(5)
+			res.IPs[0].Gateway = net.ParseIP("abcd::1")
+			res.IPs[1].Address.Mask = nil
+			res.Routes = append(res.Routes, &types.Route{GW: net.ParseIP("1.2.3.1")})
+
+			err := res.Validate()
+			Expect(err).To(HaveOccurred())
+			Expect(strings.Split(err.Error(), "\n")).To(ConsistOf(
+				"interfaces[1]: missing interface name",
+				"ips[0]: interface index 5 is out of range (result has 2 interfaces)",
+				"ips[0]: IPv6 gateway abcd::1 for IPv4 address 1.2.3.30",
+				"ips[1]: address abcd:1234:ffff::cdde has no mask",
+				"routes[2]: route has no destination",
+			))
+		})
+
+		It("rejects a route gateway in the wrong family", func() {
+			res := testResult()
+			res.Routes[0].GW = net.ParseIP("abcd::1")
+			Expect(res.Validate()).To(MatchError("routes[0]: IPv6 gateway abcd::1 for IPv4 address 15.5.6.0"))
+		})
+	})
 })
@@ -118,7 +118,8 @@ func LoadArgs(args string, container interface{}) error {
 		}
 	}
 
-	isIgnoreUnknown := GetKeyField("IgnoreUnknown", containerValue).Bool()
+	ignoreUnknown := GetKeyField("IgnoreUnknown", containerValue)
+	isIgnoreUnknown := ignoreUnknown.IsValid() && ignoreUnknown.Kind() == reflect.Bool && ignoreUnknown.Bool()
 	if len(unknownArgs) > 0 && !isIgnoreUnknown {
 		return fmt.Errorf("ARGS: unknown args %q", unknownArgs)
 	}
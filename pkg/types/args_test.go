@@ -111,6 +111,14 @@ var _ = Describe("LoadArgs", func() {
 		})
 	})
 
+	Context("When unknown arguments are passed to a struct without IgnoreUnknown", func() {
+		It("LoadArgs should fail", func() {
+			conf := struct{}{}
+			err := LoadArgs("Unk=nown", &conf)
+			Expect(err).To(MatchError(`ARGS: unknown args ["Unk=nown"]`))
+		})
+	})
+
 	Context("When known arguments are passed", func() {
 		It("LoadArgs should succeed", func() {
 			ca := CommonArgs{}
@@ -15,7 +15,11 @@
 package convert
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"reflect"
+	"sort"
 
 	"github.com/containernetworking/cni/pkg/types"
 )
@@ -50,8 +54,41 @@ func findConverter(fromVersion, toVersion string) *converter {
 	return nil
 }
 
+// findPath returns the shortest list of versions, excluding fromVersion,
+// through which a Result can be converted from fromVersion to toVersion,
+// or nil if there is none
+func findPath(fromVersion, toVersion string) []string {
+	prev := map[string]string{fromVersion: ""}
+	queue := []string{fromVersion}
+	for len(queue) > 0 {
+		v := queue[0]
+		queue = queue[1:]
+		if v == toVersion {
+			var path []string
+			for ; v != fromVersion; v = prev[v] {
+				path = append([]string{v}, path...)
+			}
+			return path
+		}
+		for _, c := range converters {
+			if c.fromVersion != v {
+				continue
+			}
+			for _, next := range c.toVersions {
+				if _, seen := prev[next]; !seen {
+					prev[next] = v
+					queue = append(queue, next)
+				}
+			}
+		}
+	}
+	return nil
+}
+
 // Convert converts a CNI Result to the requested CNI specification version,
-// or returns an error if the conversion could not be performed or failed
+// or returns an error if the conversion could not be performed or failed.
+// When there is no converter from the Result's version straight to
+// toVersion, the Result is converted through intermediate versions.
 func Convert(from types.Result, toVersion string) (types.Result, error) {
 	if toVersion == "" {
 		toVersion = "0.1.0"
@@ -64,13 +101,133 @@ func Convert(from types.Result, toVersion string) (types.Result, error) {
 		return from, nil
 	}
 
-	// Otherwise find the right converter
-	c := findConverter(fromVersion, toVersion)
-	if c == nil {
+	// Otherwise find the right converter, or chain of converters
+	if c := findConverter(fromVersion, toVersion); c != nil {
+		return c.convertFn(from, toVersion)
+	}
+	path := findPath(fromVersion, toVersion)
+	if path == nil {
 		return nil, fmt.Errorf("no converter for CNI result version %s to %s",
 			fromVersion, toVersion)
 	}
-	return c.convertFn(from, toVersion)
+	result := from
+	for _, v := range path {
+		c := findConverter(result.Version(), v)
+		next, err := c.convertFn(result, v)
+		if err != nil {
+			return nil, fmt.Errorf("converting CNI result version %s to %s: %w",
+				result.Version(), v, err)
+		}
+		result = next
+	}
+	return result, nil
+}
+
+// ConvertWithWarnings is like Convert but also returns a warning for every
+// field of from that was lost in the conversion.
+//
+// Lost fields are found by converting the result back to its original
+// version and comparing the JSON encodings of the original and the
+// round-tripped result.
+func ConvertWithWarnings(from types.Result, toVersion string) (types.Result, []types.ConversionWarning, error) {
+	if toVersion == "" {
+		toVersion = "0.1.0"
+	}
+	fromVersion := from.Version()
+
+	result, err := Convert(from, toVersion)
+	if err != nil || fromVersion == toVersion {
+		return result, nil, err
+	}
+
+	roundTripped, err := Convert(result, fromVersion)
+	if err != nil {
+		// Data cannot be compared, so the whole result may be lost
+		return result, []types.ConversionWarning{{
+			FromVersion: fromVersion,
+			ToVersion:   toVersion,
+			Field:       ".",
+			Message:     fmt.Sprintf("could not be checked for lost data: %v", err),
+		}}, nil
+	}
+
+	orig, err := resultJSON(from)
+	if err != nil {
+		return nil, nil, err
+	}
+	rt, err := resultJSON(roundTripped)
+	if err != nil {
+		return nil, nil, err
+	}
+	// The version is expected to change
+	delete(orig, "cniVersion")
+
+	var warnings []types.ConversionWarning
+	for _, lost := range lostFields(orig, rt, "") {
+		lost.FromVersion = fromVersion
+		lost.ToVersion = toVersion
+		warnings = append(warnings, lost)
+	}
+	return result, warnings, nil
+}
+
+func resultJSON(r types.Result) (map[string]interface{}, error) {
+	var buf bytes.Buffer
+	if err := r.PrintTo(&buf); err != nil {
+		return nil, err
+	}
+	m := map[string]interface{}{}
+	if err := json.Unmarshal(buf.Bytes(), &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// lostFields returns a warning, without versions, for every value of orig
+// that is missing from or different in rt
+func lostFields(orig, rt interface{}, path string) []types.ConversionWarning {
+	var warnings []types.ConversionWarning
+	switch o := orig.(type) {
+	case map[string]interface{}:
+		r, ok := rt.(map[string]interface{})
+		if !ok {
+			return []types.ConversionWarning{{Field: path, Message: "was changed"}}
+		}
+		keys := make([]string, 0, len(o))
+		for k := range o {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			field := k
+			if path != "" {
+				field = path + "." + k
+			}
+			rv, ok := r[k]
+			if !ok {
+				warnings = append(warnings, types.ConversionWarning{Field: field, Message: "was dropped"})
+				continue
+			}
+			warnings = append(warnings, lostFields(o[k], rv, field)...)
+		}
+	case []interface{}:
+		r, ok := rt.([]interface{})
+		if !ok {
+			return []types.ConversionWarning{{Field: path, Message: "was changed"}}
+		}
+		if len(o) == len(r) {
+			for i := range o {
+				warnings = append(warnings, lostFields(o[i], r[i], fmt.Sprintf("%s[%d]", path, i))...)
+			}
+		} else {
+			warnings = lostElements(o, r, path)
+		}
+	default:
+		if !reflect.DeepEqual(orig, rt) {
+			warnings = append(warnings, types.ConversionWarning{Field: path, Message: "was changed"})
+		}
+	}
+	return warnings
 }
 
 // RegisterConverter registers a CNI Result converter. SHOULD NOT BE CALLED
@@ -90,3 +247,65 @@ func RegisterConverter(fromVersion string, toVersions []string, convertFn Conver
 		convertFn:   convertFn,
 	})
 }
+
+// lostElements returns the warnings for a list whose length changed in the
+// round trip. Each element of orig is paired with the most similar
+// remaining element of rt; elements with no similar element are reported
+// as dropped.
+func lostElements(orig, rt []interface{}, path string) []types.ConversionWarning {
+	lost := make([][]types.ConversionWarning, len(orig))
+	matched := make([]bool, len(orig))
+	used := make([]bool, len(rt))
+	for {
+		bestI, bestJ := -1, -1
+		var best []types.ConversionWarning
+		for i := range orig {
+			if matched[i] {
+				continue
+			}
+			for j := range rt {
+				if used[j] {
+					continue
+				}
+				w := lostFields(orig[i], rt[j], fmt.Sprintf("%s[%d]", path, i))
+				if bestI < 0 || len(w) < len(best) {
+					bestI, bestJ, best = i, j, w
+				}
+			}
+		}
+		if bestI < 0 || len(best) >= leafCount(orig[bestI]) {
+			break
+		}
+		matched[bestI], used[bestJ], lost[bestI] = true, true, best
+	}
+
+	var warnings []types.ConversionWarning
+	for i := range orig {
+		if !matched[i] {
+			warnings = append(warnings, types.ConversionWarning{Field: fmt.Sprintf("%s[%d]", path, i), Message: "was dropped"})
+			continue
+		}
+		warnings = append(warnings, lost[i]...)
+	}
+	return warnings
+}
+
+// leafCount returns the number of scalar values in v
+func leafCount(v interface{}) int {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		n := 0
+		for _, e := range t {
+			n += leafCount(e)
+		}
+		return n
+	case []interface{}:
+		n := 0
+		for _, e := range t {
+			n += leafCount(e)
+		}
+		return n
+	default:
+		return 1
+	}
+}
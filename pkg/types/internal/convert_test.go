@@ -0,0 +1,133 @@
+// Copyright 2024 CNI authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package convert_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/containernetworking/cni/pkg/types"
+	convert "github.com/containernetworking/cni/pkg/types/internal"
+)
+
+// fakeResult is a result of one of the made-up versions 9.0.0, 9.1.0 and
+// 9.2.0. Only 9.2.0 supports Extra.
+type fakeResult struct {
+	CNIVersion string   `json:"cniVersion"`
+	Names      []string `json:"names,omitempty"`
+	Extra      string   `json:"extra,omitempty"`
+}
+
+func (r *fakeResult) Version() string { return r.CNIVersion }
+
+func (r *fakeResult) GetAsVersion(version string) (types.Result, error) {
+	return convert.Convert(r, version)
+}
+
+func (r *fakeResult) Print() error { return r.PrintTo(os.Stdout) }
+
+func (r *fakeResult) PrintTo(w io.Writer) error {
+	return json.NewEncoder(w).Encode(r)
+}
+
+var conversions []string
+
+func fakeConverter(from types.Result, toVersion string) (types.Result, error) {
+	r := from.(*fakeResult)
+	conversions = append(conversions, r.CNIVersion+"->"+toVersion)
+	if r.Extra == "fail" {
+		return nil, fmt.Errorf("cannot convert")
+	}
+	to := &fakeResult{CNIVersion: toVersion, Names: r.Names, Extra: r.Extra}
+	if toVersion != "9.2.0" {
+		to.Extra = ""
+	}
+	// 9.0.0 only supports one name
+	if toVersion == "9.0.0" && len(to.Names) > 1 {
+		to.Names = to.Names[1:2]
+	}
+	return to, nil
+}
+
+func init() {
+	// 9.0.0 and 9.2.0 can only be converted through 9.1.0
+	convert.RegisterConverter("9.0.0", []string{"9.1.0"}, fakeConverter)
+	convert.RegisterConverter("9.1.0", []string{"9.0.0", "9.2.0"}, fakeConverter)
+	convert.RegisterConverter("9.2.0", []string{"9.1.0"}, fakeConverter)
+}
+
+var _ = Describe("Convert", func() {
+	BeforeEach(func() {
+		conversions = nil
+	})
+
+	It("converts through intermediate versions", func() {
+		result, err := convert.Convert(&fakeResult{CNIVersion: "9.0.0", Names: []string{"a"}}, "9.2.0")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal(&fakeResult{CNIVersion: "9.2.0", Names: []string{"a"}}))
+		Expect(conversions).To(Equal([]string{"9.0.0->9.1.0", "9.1.0->9.2.0"}))
+	})
+
+	It("uses a direct converter when there is one", func() {
+		_, err := convert.Convert(&fakeResult{CNIVersion: "9.1.0"}, "9.2.0")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(conversions).To(Equal([]string{"9.1.0->9.2.0"}))
+	})
+
+	It("returns an error when there is no path", func() {
+		_, err := convert.Convert(&fakeResult{CNIVersion: "9.0.0"}, "8.0.0")
+		Expect(err).To(MatchError("no converter for CNI result version 9.0.0 to 8.0.0"))
+	})
+
+	It("returns an error identifying the failing step", func() {
+		_, err := convert.Convert(&fakeResult{CNIVersion: "9.2.0", Extra: "fail"}, "9.0.0")
+		Expect(err).To(MatchError("converting CNI result version 9.2.0 to 9.1.0: cannot convert"))
+	})
+
+	Describe("ConvertWithWarnings", func() {
+		It("reports no warnings for a lossless conversion", func() {
+			result, warnings, err := convert.ConvertWithWarnings(&fakeResult{CNIVersion: "9.0.0", Names: []string{"a"}}, "9.2.0")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Version()).To(Equal("9.2.0"))
+			Expect(warnings).To(BeEmpty())
+		})
+
+		It("reports every lost field", func() {
+			from := &fakeResult{CNIVersion: "9.2.0", Names: []string{"a", "b", "c"}, Extra: "x"}
+			result, warnings, err := convert.ConvertWithWarnings(from, "9.0.0")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result).To(Equal(&fakeResult{CNIVersion: "9.0.0", Names: []string{"b"}}))
+			Expect(warnings).To(Equal([]types.ConversionWarning{
+				{FromVersion: "9.2.0", ToVersion: "9.0.0", Field: "extra", Message: "was dropped"},
+				{FromVersion: "9.2.0", ToVersion: "9.0.0", Field: "names[0]", Message: "was dropped"},
+				{FromVersion: "9.2.0", ToVersion: "9.0.0", Field: "names[2]", Message: "was dropped"},
+			}))
+			Expect(warnings[0].String()).To(Equal("converting result from 9.2.0 to 9.0.0: extra was dropped"))
+		})
+
+		It("reports no warnings for the same version", func() {
+			from := &fakeResult{CNIVersion: "9.2.0", Extra: "x"}
+			result, warnings, err := convert.ConvertWithWarnings(from, "9.2.0")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result).To(BeIdenticalTo(from))
+			Expect(warnings).To(BeEmpty())
+		})
+	})
+})
@@ -0,0 +1,94 @@
+// Copyright 2024 CNI authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package convert
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/containernetworking/cni/pkg/types"
+)
+
+// The helpers below implement the checks shared by the Validate() methods
+// of the versioned Result types.
+
+func family(ip net.IP) string {
+	if ip.To4() != nil {
+		return "IPv4"
+	}
+	return "IPv6"
+}
+
+// ValidateIPNet checks that n has an IP address and a canonical mask of
+// the same address family.
+func ValidateIPNet(n *net.IPNet) error {
+	if n.IP == nil {
+		return fmt.Errorf("missing IP address")
+	}
+	if n.IP.To16() == nil {
+		return fmt.Errorf("invalid IP address %v", n.IP)
+	}
+	if n.Mask == nil {
+		return fmt.Errorf("address %v has no mask", n.IP)
+	}
+	_, bits := n.Mask.Size()
+	wantBits := 128
+	if n.IP.To4() != nil {
+		wantBits = 32
+	}
+	if bits != wantBits {
+		return fmt.Errorf("address %v has an invalid %s mask %v", n.IP, family(n.IP), n.Mask)
+	}
+	return nil
+}
+
+// ValidateGateway checks that gw, if set, is in the same address family as
+// ip.
+func ValidateGateway(gw, ip net.IP) error {
+	if gw == nil || ip == nil {
+		return nil
+	}
+	if family(gw) != family(ip) {
+		return fmt.Errorf("%s gateway %v for %s address %v", family(gw), gw, family(ip), ip)
+	}
+	return nil
+}
+
+// ValidateRoute checks that r has a valid destination and that its gateway,
+// if set, is in the destination's address family.
+func ValidateRoute(r *types.Route) error {
+	if r == nil {
+		return fmt.Errorf("missing route")
+	}
+	if r.Dst.IP == nil {
+		return fmt.Errorf("route has no destination")
+	}
+	if err := ValidateIPNet(&r.Dst); err != nil {
+		return fmt.Errorf("route destination: %w", err)
+	}
+	return ValidateGateway(r.GW, r.Dst.IP)
+}
+
+// ValidateInterfaceIndex checks that idx, if set, refers to one of
+// numInterfaces interfaces.
+func ValidateInterfaceIndex(idx *int, numInterfaces int) error {
+	if idx == nil {
+		return nil
+	}
+	if *idx < 0 || *idx >= numInterfaces {
+		return fmt.Errorf("interface index %d is out of range (result has %d interfaces)", *idx, numInterfaces)
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+// Copyright 2024 CNI authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"net"
+	"net/netip"
+)
+
+// The functions below convert between the net.IP/net.IPNet values used by
+// the result types and their net/netip equivalents. Values converted from
+// net/netip have the same shape as those produced by ParseCIDR and
+// net.ParseIP (16-byte addresses, and 4-byte masks for IPv4), so they
+// compare equal to values decoded from JSON.
+
+// AddrFromIP converts ip to a netip.Addr. IPv4-mapped IPv6 addresses are
+// returned as IPv4 addresses. It returns the zero Addr if ip is invalid.
+func AddrFromIP(ip net.IP) netip.Addr {
+	addr, ok := netip.AddrFromSlice(ip)
+	if !ok {
+		return netip.Addr{}
+	}
+	return addr.Unmap()
+}
+
+// IPFromAddr converts addr to a net.IP, or nil if addr is the zero Addr.
+// Any IPv6 zone is dropped.
+func IPFromAddr(addr netip.Addr) net.IP {
+	if !addr.IsValid() {
+		return nil
+	}
+	ip := addr.Unmap().As16()
+	return net.IP(ip[:])
+}
+
+// PrefixFromIPNet converts n to a netip.Prefix, keeping the host bits of
+// n.IP as ParseCIDR does. It returns the zero Prefix if n has an invalid
+// address, or a mask that is non-canonical or of the wrong family.
+func PrefixFromIPNet(n net.IPNet) netip.Prefix {
+	addr := AddrFromIP(n.IP)
+	if !addr.IsValid() {
+		return netip.Prefix{}
+	}
+	ones, bits := n.Mask.Size()
+	if bits != addr.BitLen() {
+		return netip.Prefix{}
+	}
+	return netip.PrefixFrom(addr, ones)
+}
+
+// IPNetFromPrefix converts p to a net.IPNet, keeping the host bits of
+// p.Addr(). It returns the zero IPNet if p is invalid.
+func IPNetFromPrefix(p netip.Prefix) net.IPNet {
+	if !p.IsValid() {
+		return net.IPNet{}
+	}
+	bits := 128
+	if p.Addr().Unmap().Is4() {
+		bits = 32
+	}
+	return net.IPNet{
+		IP:   IPFromAddr(p.Addr()),
+		Mask: net.CIDRMask(p.Bits(), bits),
+	}
+}
+
+// ParsePrefix is like ParseCIDR but returns a netip.Prefix. The host bits
+// of the address are kept, e.g. "10.2.3.1/24" is returned as is.
+func ParsePrefix(s string) (netip.Prefix, error) {
+	n, err := ParseCIDR(s)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	return PrefixFromIPNet(*n), nil
+}
+
+// Prefix returns n as a netip.Prefix. See PrefixFromIPNet.
+func (n IPNet) Prefix() netip.Prefix {
+	return PrefixFromIPNet(net.IPNet(n))
+}
+
+// NewRoute returns a route to dst via gw. gw may be the zero Addr for a
+// route without a gateway.
+func NewRoute(dst netip.Prefix, gw netip.Addr) *Route {
+	return &Route{
+		Dst: IPNetFromPrefix(dst.Masked()),
+		GW:  IPFromAddr(gw),
+	}
+}
+
+// DstPrefix returns the destination of the route as a netip.Prefix.
+func (r *Route) DstPrefix() netip.Prefix {
+	return PrefixFromIPNet(r.Dst)
+}
+
+// GWAddr returns the gateway of the route as a netip.Addr, or the zero
+// Addr if the route has no gateway.
+func (r *Route) GWAddr() netip.Addr {
+	return AddrFromIP(r.GW)
+}
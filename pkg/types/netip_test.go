@@ -0,0 +1,109 @@
+// Copyright 2024 CNI authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types_test
+
+import (
+	"encoding/json"
+	"net"
+	"net/netip"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/containernetworking/cni/pkg/types"
+)
+
+var _ = Describe("netip conversions", func() {
+	DescribeTable("converts between net.IPNet and netip.Prefix",
+		func(cidr string) {
+			ipn, err := types.ParseCIDR(cidr)
+			Expect(err).NotTo(HaveOccurred())
+
+			prefix, err := types.ParsePrefix(cidr)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(prefix).To(Equal(netip.MustParsePrefix(cidr)))
+			Expect(types.PrefixFromIPNet(*ipn)).To(Equal(prefix))
+			Expect(types.IPNet(*ipn).Prefix()).To(Equal(prefix))
+
+			// Converting back gives the same value as ParseCIDR
+			Expect(types.IPNetFromPrefix(prefix)).To(Equal(*ipn))
+		},
+		Entry("IPv4 with host bits", "10.1.2.3/24"),
+		Entry("IPv4 network", "10.1.2.0/24"),
+		Entry("IPv4 default", "0.0.0.0/0"),
+		Entry("IPv6 with host bits", "fd00::3/64"),
+		Entry("IPv6 default", "::/0"),
+	)
+
+	It("converts between net.IP and netip.Addr", func() {
+		Expect(types.AddrFromIP(net.ParseIP("10.1.2.3"))).To(Equal(netip.MustParseAddr("10.1.2.3")))
+		Expect(types.AddrFromIP(net.ParseIP("10.1.2.3").To4())).To(Equal(netip.MustParseAddr("10.1.2.3")))
+		Expect(types.AddrFromIP(net.ParseIP("fd00::1"))).To(Equal(netip.MustParseAddr("fd00::1")))
+		Expect(types.IPFromAddr(netip.MustParseAddr("10.1.2.3"))).To(Equal(net.ParseIP("10.1.2.3")))
+		Expect(types.IPFromAddr(netip.MustParseAddr("fd00::1"))).To(Equal(net.ParseIP("fd00::1")))
+	})
+
+	It("returns zero values for missing or invalid input", func() {
+		Expect(types.AddrFromIP(nil).IsValid()).To(BeFalse())
+		Expect(types.IPFromAddr(netip.Addr{})).To(BeNil())
+		Expect(types.PrefixFromIPNet(net.IPNet{}).IsValid()).To(BeFalse())
+		Expect(types.IPNetFromPrefix(netip.Prefix{})).To(Equal(net.IPNet{}))
+
+		// an IPv6 mask on an IPv4 address
+		ipn := net.IPNet{IP: net.ParseIP("10.1.2.3"), Mask: net.CIDRMask(24, 128)}
+		Expect(types.PrefixFromIPNet(ipn).IsValid()).To(BeFalse())
+
+		_, err := types.ParsePrefix("10.1.2.3")
+		Expect(err).To(HaveOccurred())
+	})
+
+	Describe("routes", func() {
+		It("builds a route from netip values", func() {
+			route := types.NewRoute(netip.MustParsePrefix("10.1.2.3/24"), netip.MustParseAddr("10.1.2.1"))
+			Expect(route.DstPrefix()).To(Equal(netip.MustParsePrefix("10.1.2.0/24")))
+			Expect(route.GWAddr()).To(Equal(netip.MustParseAddr("10.1.2.1")))
+
+			// and marshals it exactly like one decoded from JSON
+			jsonBytes, err := json.Marshal(route)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(jsonBytes).To(MatchJSON(`{ "dst": "10.1.2.0/24", "gw": "10.1.2.1" }`))
+
+			var unmarshaled types.Route
+			Expect(json.Unmarshal(jsonBytes, &unmarshaled)).To(Succeed())
+			Expect(&unmarshaled).To(Equal(route))
+		})
+
+		It("builds a route without a gateway", func() {
+			route := types.NewRoute(netip.MustParsePrefix("::/0"), netip.Addr{})
+			Expect(route.GW).To(BeNil())
+			Expect(route.GWAddr().IsValid()).To(BeFalse())
+
+			jsonBytes, err := json.Marshal(route)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(jsonBytes).To(MatchJSON(`{ "dst": "::/0" }`))
+		})
+
+		It("can be used as a map key", func() {
+			seen := map[netip.Prefix]bool{}
+			for _, dst := range []string{"10.0.0.0/8", "10.0.0.0/8", "fd00::/8"} {
+				_, ipn, err := net.ParseCIDR(dst)
+				Expect(err).NotTo(HaveOccurred())
+				route := types.Route{Dst: *ipn}
+				seen[route.DstPrefix()] = true
+			}
+			Expect(seen).To(HaveLen(2))
+		})
+	})
+})
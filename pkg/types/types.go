@@ -120,6 +120,46 @@ type Result interface {
 	PrintTo(writer io.Writer) error
 }
 
+// ConversionWarning describes data that was lost when a Result was
+// converted to another CNI specification version, e.g. the interfaces of a
+// 1.0.0 result converted to 0.2.0.
+type ConversionWarning struct {
+	FromVersion string
+	ToVersion   string
+	// Field is the JSON path of the lost data in the original result,
+	// e.g. "interfaces" or "ips[1].interface"
+	Field string
+	// Message describes what happened to the field
+	Message string
+}
+
+func (w ConversionWarning) String() string {
+	return fmt.Sprintf("converting result from %s to %s: %s %s", w.FromVersion, w.ToVersion, w.Field, w.Message)
+}
+
+// WarningResult is implemented by Results that can report the data lost
+// when they are converted to another CNI specification version.
+type WarningResult interface {
+	Result
+
+	// GetAsVersionWithWarnings is like GetAsVersion but also returns a
+	// warning for every field of the result that could not be represented
+	// in the requested version
+	GetAsVersionWithWarnings(version string) (Result, []ConversionWarning, error)
+}
+
+// GetAsVersionWithWarnings converts result to the requested CNI
+// specification version, returning a warning for every field that was lost
+// in the conversion. Results that do not implement WarningResult are
+// converted with GetAsVersion and never produce warnings.
+func GetAsVersionWithWarnings(result Result, version string) (Result, []ConversionWarning, error) {
+	if wr, ok := result.(WarningResult); ok {
+		return wr.GetAsVersionWithWarnings(version)
+	}
+	newResult, err := result.GetAsVersion(version)
+	return newResult, nil, err
+}
+
 func PrintResult(result Result, version string) error {
 	newResult, err := result.GetAsVersion(version)
 	if err != nil {
@@ -151,10 +191,36 @@ func (d *DNS) Copy() *DNS {
 type Route struct {
 	Dst net.IPNet
 	GW  net.IP
+
+	// The following fields were added in CNI spec version 1.1.0 and are
+	// left at their zero values for older versions
+	MTU      int
+	AdvMSS   int
+	Priority int
+	Table    *int
+	Scope    *int
 }
 
 func (r *Route) String() string {
-	return fmt.Sprintf("%+v", *r)
+	s := fmt.Sprintf("{Dst:%+v GW:%v", r.Dst, r.GW)
+	// Only include the 1.1.0 fields that are set, so routes of older
+	// versions print as before
+	if r.MTU != 0 {
+		s += fmt.Sprintf(" MTU:%d", r.MTU)
+	}
+	if r.AdvMSS != 0 {
+		s += fmt.Sprintf(" AdvMSS:%d", r.AdvMSS)
+	}
+	if r.Priority != 0 {
+		s += fmt.Sprintf(" Priority:%d", r.Priority)
+	}
+	if r.Table != nil {
+		s += fmt.Sprintf(" Table:%d", *r.Table)
+	}
+	if r.Scope != nil {
+		s += fmt.Sprintf(" Scope:%d", *r.Scope)
+	}
+	return s + "}"
 }
 
 func (r *Route) Copy() *Route {
@@ -162,10 +228,22 @@ func (r *Route) Copy() *Route {
 		return nil
 	}
 
-	return &Route{
-		Dst: r.Dst,
-		GW:  r.GW,
+	route := &Route{
+		Dst:      r.Dst,
+		GW:       r.GW,
+		MTU:      r.MTU,
+		AdvMSS:   r.AdvMSS,
+		Priority: r.Priority,
+	}
+	if r.Table != nil {
+		table := *r.Table
+		route.Table = &table
+	}
+	if r.Scope != nil {
+		scope := *r.Scope
+		route.Scope = &scope
 	}
+	return route
 }
 
 // Well known error codes
@@ -215,8 +293,13 @@ func (e *Error) Print() error {
 
 // JSON (un)marshallable types
 type route struct {
-	Dst IPNet  `json:"dst"`
-	GW  net.IP `json:"gw,omitempty"`
+	Dst      IPNet  `json:"dst"`
+	GW       net.IP `json:"gw,omitempty"`
+	MTU      int    `json:"mtu,omitempty"`
+	AdvMSS   int    `json:"advmss,omitempty"`
+	Priority int    `json:"priority,omitempty"`
+	Table    *int   `json:"table,omitempty"`
+	Scope    *int   `json:"scope,omitempty"`
 }
 
 func (r *Route) UnmarshalJSON(data []byte) error {
@@ -227,13 +310,23 @@ func (r *Route) UnmarshalJSON(data []byte) error {
 
 	r.Dst = net.IPNet(rt.Dst)
 	r.GW = rt.GW
+	r.MTU = rt.MTU
+	r.AdvMSS = rt.AdvMSS
+	r.Priority = rt.Priority
+	r.Table = rt.Table
+	r.Scope = rt.Scope
 	return nil
 }
 
 func (r Route) MarshalJSON() ([]byte, error) {
 	rt := route{
-		Dst: IPNet(r.Dst),
-		GW:  r.GW,
+		Dst:      IPNet(r.Dst),
+		GW:       r.GW,
+		MTU:      r.MTU,
+		AdvMSS:   r.AdvMSS,
+		Priority: r.Priority,
+		Table:    r.Table,
+		Scope:    r.Scope,
 	}
 
 	return json.Marshal(rt)
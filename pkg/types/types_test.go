@@ -112,6 +112,56 @@ var _ = Describe("Types", func() {
 		It("formats as a string with a hex mask", func() {
 			Expect(example.String()).To(Equal(`{Dst:{IP:1.2.3.0 Mask:ffffff00} GW:1.2.3.1}`))
 		})
+
+		Context("when the route has the fields added in spec version 1.1.0", func() {
+			BeforeEach(func() {
+				table, scope := 100, 253
+				example.MTU = 1450
+				example.AdvMSS = 1400
+				example.Priority = 10
+				example.Table = &table
+				example.Scope = &scope
+			})
+
+			It("marshals and unmarshals to JSON", func() {
+				jsonBytes, err := json.Marshal(example)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(jsonBytes).To(MatchJSON(`{
+					"dst": "1.2.3.0/24",
+					"gw": "1.2.3.1",
+					"mtu": 1450,
+					"advmss": 1400,
+					"priority": 10,
+					"table": 100,
+					"scope": 253
+				}`))
+
+				var unmarshaled types.Route
+				Expect(json.Unmarshal(jsonBytes, &unmarshaled)).To(Succeed())
+				Expect(unmarshaled).To(Equal(example))
+			})
+
+			It("marshals a zero table and scope", func() {
+				table, scope := 0, 0
+				example = types.Route{Dst: example.Dst, Table: &table, Scope: &scope}
+				jsonBytes, err := json.Marshal(example)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(jsonBytes).To(MatchJSON(`{ "dst": "1.2.3.0/24", "table": 0, "scope": 0 }`))
+			})
+
+			It("deep copies the table and scope", func() {
+				route := example.Copy()
+				Expect(route).To(Equal(&example))
+				*route.Table = 200
+				*route.Scope = 0
+				Expect(*example.Table).To(Equal(100))
+				Expect(*example.Scope).To(Equal(253))
+			})
+
+			It("includes them in its string form", func() {
+				Expect(example.String()).To(Equal(`{Dst:{IP:1.2.3.0 Mask:ffffff00} GW:1.2.3.1 MTU:1450 AdvMSS:1400 Priority:10 Table:100 Scope:253}`))
+			})
+		})
 	})
 
 	Describe("Error type", func() {
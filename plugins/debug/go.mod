@@ -1,6 +1,6 @@
 module github.com/containernetworking/cni/plugins/debug
 
-go 1.18
+go 1.21
 
 require (
 	github.com/containernetworking/cni v1.1.2
@@ -9,7 +9,7 @@ require (
 
 require (
 	github.com/vishvananda/netns v0.0.4 // indirect
-	golang.org/x/sys v0.12.0 // indirect
+	golang.org/x/sys v0.14.0 // indirect
 )
 
 replace github.com/containernetworking/cni => ../..
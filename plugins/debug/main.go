@@ -15,15 +15,15 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
 
-	"github.com/containernetworking/plugins/pkg/ns"
 	bv "github.com/containernetworking/plugins/pkg/utils/buildversion"
 
+	"github.com/containernetworking/cni/pkg/ns"
 	"github.com/containernetworking/cni/pkg/skel"
 	"github.com/containernetworking/cni/pkg/types"
 	type100 "github.com/containernetworking/cni/pkg/types/100"
@@ -38,8 +38,14 @@ type NetConf struct {
 	CheckHooks [][]string `json:"checkHooks,omitempty"`
 }
 
+type cmdArgs = skel.TypedCmdArgs[NetConf, struct{}]
+
 func main() {
-	skel.PluginMain(cmdAdd, cmdCheck, cmdDel, version.All, bv.BuildString("none"))
+	skel.PluginMainTyped(skel.TypedCNIFuncs[NetConf, struct{}]{
+		Add:   cmdAdd,
+		Check: cmdCheck,
+		Del:   cmdDel,
+	}, version.All, bv.BuildString("none"))
 }
 
 func outputCmdArgs(fp io.Writer, args *skel.CmdArgs) {
@@ -59,25 +65,6 @@ StdinData: %s
 		string(args.StdinData))
 }
 
-func parseConf(data []byte) (*NetConf, error) {
-	conf := &NetConf{}
-	if err := json.Unmarshal(data, &conf); err != nil {
-		return nil, fmt.Errorf("failed to parse")
-	}
-
-	return conf, nil
-}
-
-func getResult(netConf *NetConf) *type100.Result {
-	if netConf.RawPrevResult == nil {
-		return &type100.Result{}
-	}
-
-	version.ParsePrevResult(&netConf.NetConf)
-	result, _ := type100.NewResultFromResult(netConf.PrevResult)
-	return result
-}
-
 func executeHooks(netnsName string, hooks [][]string) {
 	netns, err := ns.GetNS(netnsName)
 	if err != nil {
@@ -99,51 +86,54 @@ func executeHooks(netnsName string, hooks [][]string) {
 	})
 }
 
-func cmdAdd(args *skel.CmdArgs) error {
-	/*自标准输出中解析networkConfig*/
-	netConf, _ := parseConf(args.StdinData)
+func cmdAdd(_ context.Context, args *cmdArgs) error {
+	netConf := args.Conf
 	// Output CNI
 	if netConf.CNIOutput != "" {
 		fp, _ := os.OpenFile(netConf.CNIOutput, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
 		defer fp.Close()
 		fmt.Fprintf(fp, "CmdAdd\n")
-		outputCmdArgs(fp, args)
+		outputCmdArgs(fp, args.CmdArgs)
 	}
 	// call hooks
 	if netConf.AddHooks != nil {
 		executeHooks(args.Netns, netConf.AddHooks)
 	}
-	return types.PrintResult(getResult(netConf), netConf.CNIVersion)
+	result := args.PrevResult
+	if result == nil {
+		result = &type100.Result{}
+	}
+	return args.PrintResult(result, netConf.CNIVersion)
 }
 
-func cmdDel(args *skel.CmdArgs) error {
-	netConf, _ := parseConf(args.StdinData)
+func cmdDel(_ context.Context, args *cmdArgs) error {
+	netConf := args.Conf
 	// Output CNI
 	if netConf.CNIOutput != "" {
 		fp, _ := os.OpenFile(netConf.CNIOutput, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
 		defer fp.Close()
 		fmt.Fprintf(fp, "CmdDel\n")
-		outputCmdArgs(fp, args)
+		outputCmdArgs(fp, args.CmdArgs)
 	}
 	// call hooks
 	if netConf.DelHooks != nil {
 		executeHooks(args.Netns, netConf.DelHooks)
 	}
-	return types.PrintResult(&type100.Result{}, netConf.CNIVersion)
+	return args.PrintResult(&type100.Result{}, netConf.CNIVersion)
 }
 
-func cmdCheck(args *skel.CmdArgs) error {
-	netConf, _ := parseConf(args.StdinData)
+func cmdCheck(_ context.Context, args *cmdArgs) error {
+	netConf := args.Conf
 	// Output CNI
 	if netConf.CNIOutput != "" {
 		fp, _ := os.OpenFile(netConf.CNIOutput, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
 		defer fp.Close()
 		fmt.Fprintf(fp, "CmdCheck\n")
-		outputCmdArgs(fp, args)
+		outputCmdArgs(fp, args.CmdArgs)
 	}
 	// call hooks
 	if netConf.CheckHooks != nil {
 		executeHooks(args.Netns, netConf.CheckHooks)
 	}
-	return types.PrintResult(&type100.Result{}, netConf.CNIVersion)
+	return args.PrintResult(&type100.Result{}, netConf.CNIVersion)
 }